@@ -1,37 +1,87 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // grant types
 const (
-	GrantTypePassword = "password"
+	GrantTypePassword          = "password"
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeInstalledClient   = "https://oauth.reddit.com/grants/installed_client"
+)
+
+// durations for the authorization code flow
+const (
+	DurationTemporary = "temporary"
+	DurationPermanent = "permanent"
 )
 
+// tokenRefreshMargin is how long before its expiry a token will be
+// refreshed
+const tokenRefreshMargin = time.Minute
+
 // RedditAccount holds the data pertaining to a reddit account
 type RedditAccount struct {
 	API      *RedditAPI
 	Username string
 	// Password should not be stored
 
+	// DeviceID is the device ID used with the installed client
+	// grant
+	DeviceID string
+
 	Token *Token
+
+	// Store, if set, is used to reuse tokens between runs. It is
+	// checked before logging in and updated after every login and
+	// refresh.
+	Store TokenStore
+
+	// mu guards Token so that only one refresh happens at a time
+	mu sync.Mutex
 }
 
 // Token stores the authentication token and expiry time so that the
 // validity of the token can be automatically verified before
 // requests.
 type Token struct {
-	Token     string         `json:"access_token"`
-	TokenType string         `json:"token_type"`
-	Scope     string         `json:"scope"`
-	ExpiresIn TokenExpiresIn `json:"expires_in"` // seconds
-	Expiry    time.Time
-	Error     string `json:"error"`
+	Token        string         `json:"access_token"`
+	TokenType    string         `json:"token_type"`
+	Scope        string         `json:"scope"`
+	ExpiresIn    TokenExpiresIn `json:"expires_in"` // seconds
+	RefreshToken string         `json:"refresh_token"`
+	GrantType    string         `json:"grant_type"`
+	DeviceID     string         `json:"device_id"` // only for the installed client grant
+	Username     string         `json:"username"`  // blank for application-only tokens
+	Expiry       time.Time
+	Error        string `json:"error"`
+}
+
+// AppOnly reports whether the token was issued to the application
+// rather than a user, in which case it can only be used to read
+func (t *Token) AppOnly() bool {
+	return t.GrantType == GrantTypeClientCredentials || t.GrantType == GrantTypeInstalledClient
+}
+
+// usable reports whether the token is valid, or can be renewed
+// without any user input
+func (t *Token) usable() bool {
+	if t.Token == "" {
+		return false
+	}
+	return time.Now().Add(tokenRefreshMargin).Before(t.Expiry) || t.RefreshToken != "" || t.AppOnly()
 }
 
 // TokenExpiresIn is a Duration with custom unmarshaller for
@@ -44,36 +94,332 @@ func (t *TokenExpiresIn) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &int64_duration); err != nil {
 		return err
 	}
-	fmt.Printf("%d\n", int64_duration)
 	int64_duration *= int64(time.Second)
 	*t = TokenExpiresIn(int64_duration)
 
 	return nil
 }
 
+// MarshalJSON encodes this as seconds, so that it can be decoded again
+func (t TokenExpiresIn) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int64(time.Duration(t) / time.Second))
+}
+
+// AuthorizeURL returns the URL a user should visit to grant this app
+// access to their account. Reddit will redirect back to redirectURI
+// with the given state and a code to be passed to CodeLogin. Use
+// DurationPermanent to be issued a refresh token.
+func (api *RedditAPI) AuthorizeURL(state, redirectURI, duration string, scopes ...string) *url.URL {
+	u := api.GetRedditURL(RedditEndpointAuthorize)
+	u.RawQuery = url.Values{
+		"client_id":     {api.ClientID},
+		"response_type": {"code"},
+		"state":         {state},
+		"redirect_uri":  {redirectURI},
+		"duration":      {duration},
+		"scope":         {strings.Join(scopes, " ")},
+	}.Encode()
+	return u
+}
+
 // PasswordLogin uses a password to authenticate, storing the access
 // token in the RedditAccount. Returns an error.
 func (a *RedditAccount) PasswordLogin(password string) error {
-	// get the URL for logging in
-	redditURL := GetRedditURL(RedditEndpointLogin)
+	return a.PasswordLoginContext(context.Background(), password)
+}
+
+// PasswordLoginContext is like PasswordLogin but with a context
+func (a *RedditAccount) PasswordLoginContext(ctx context.Context, password string) error {
+	// reuse a stored token if there is one
+	if a.restoreToken(GrantTypePassword, "") {
+		return nil
+	}
 
-	// construct POST data
-	data := url.Values{
+	token, err := a.requestToken(ctx, url.Values{
 		"grant_type": {GrantTypePassword},
 		"username":   {a.Username},
 		"password":   {password},
+	})
+	if err != nil {
+		return err
 	}
 
-	// send request
-	resp, err := a.API.PostForm(redditURL, data)
+	return a.setToken(token)
+}
+
+// CodeLogin exchanges the code returned to the redirect URI by the
+// authorize page for an access token, storing it in the
+// RedditAccount. redirectURI must match the one used to build the
+// authorize URL.
+func (a *RedditAccount) CodeLogin(code, redirectURI string) error {
+	return a.CodeLoginContext(context.Background(), code, redirectURI)
+}
+
+// CodeLoginContext is like CodeLogin but with a context
+func (a *RedditAccount) CodeLoginContext(ctx context.Context, code, redirectURI string) error {
+	token, err := a.requestToken(ctx, url.Values{
+		"grant_type":   {GrantTypeAuthorizationCode},
+		"code":         {code},
+		"redirect_uri": {redirectURI},
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.setToken(token)
+}
+
+// ClientCredentialsLogin authenticates as the application itself
+// using the client ID and secret, without a user account. The token
+// can only be used for read-only requests.
+func (a *RedditAccount) ClientCredentialsLogin() error {
+	return a.ClientCredentialsLoginContext(context.Background())
+}
+
+// ClientCredentialsLoginContext is like ClientCredentialsLogin but with a context
+func (a *RedditAccount) ClientCredentialsLoginContext(ctx context.Context) error {
+	// reuse a stored token if there is one
+	if a.restoreToken(GrantTypeClientCredentials, "") {
+		return nil
+	}
+
+	token, err := a.requestToken(ctx, url.Values{
+		"grant_type": {GrantTypeClientCredentials},
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.setToken(token)
+}
+
+// InstalledClientLogin authenticates as an installed application
+// without a user account. deviceID should be a unique 20-30
+// character ID per device, or "DO_NOT_TRACK_THIS_DEVICE". The token
+// can only be used for read-only requests.
+func (a *RedditAccount) InstalledClientLogin(deviceID string) error {
+	return a.InstalledClientLoginContext(context.Background(), deviceID)
+}
+
+// InstalledClientLoginContext is like InstalledClientLogin but with a context
+func (a *RedditAccount) InstalledClientLoginContext(ctx context.Context, deviceID string) error {
+	// reuse a stored token if there is one
+	if a.restoreToken(GrantTypeInstalledClient, deviceID) {
+		return nil
+	}
+
+	token, err := a.requestToken(ctx, url.Values{
+		"grant_type": {GrantTypeInstalledClient},
+		"device_id":  {deviceID},
+	})
+	if err != nil {
+		return err
+	}
+
+	return a.setToken(token)
+}
+
+// RestoreToken loads a token from the Store without logging in. This
+// is useful with the authorization code flow, where logging in again
+// would require user input. An error is returned if there is no
+// stored token that is valid or can be refreshed.
+func (a *RedditAccount) RestoreToken() error {
+	if a.Store == nil {
+		return errors.New("no token store")
+	}
+	if !a.restoreToken("", "") {
+		return ErrNoStoredToken
+	}
+	return nil
+}
+
+// Refresh uses the refresh token to obtain a new access token, or
+// requests a new token if the current one is application-only. This
+// is done automatically before requests when the token is about to
+// expire, so it should rarely need to be called directly.
+func (a *RedditAccount) Refresh() error {
+	return a.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh but with a context
+func (a *RedditAccount) RefreshContext(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.refresh(ctx); err != nil {
+		return err
+	}
+	return a.saveToken()
+}
+
+// refresh performs the refresh. a.mu must be held by the caller.
+func (a *RedditAccount) refresh(ctx context.Context) error {
+	if a.Token != nil && a.Token.AppOnly() {
+		// app-only tokens are never given a refresh token, but
+		// a new one can be requested without any user input
+		data := url.Values{
+			"grant_type": {a.Token.GrantType},
+		}
+		if a.Token.GrantType == GrantTypeInstalledClient {
+			data.Set("device_id", a.Token.DeviceID)
+		}
+		token, err := a.requestToken(ctx, data)
+		if err != nil {
+			return err
+		}
+		a.Token = token
+		return nil
+	}
+	if a.Token == nil || a.Token.RefreshToken == "" {
+		return ErrNoRefreshToken
+	}
+
+	token, err := a.requestToken(ctx, url.Values{
+		"grant_type":    {GrantTypeRefreshToken},
+		"refresh_token": {a.Token.RefreshToken},
+	})
 	if err != nil {
 		return err
 	}
+
+	// reddit does not send the refresh token again, so carry it over
+	if token.RefreshToken == "" {
+		token.RefreshToken = a.Token.RefreshToken
+	}
+	token.GrantType = a.Token.GrantType
+	token.Username = a.Token.Username
+	a.Token = token
+
+	return nil
+}
+
+// accessToken returns a valid token, refreshing it first if it has
+// expired or is about to expire. Concurrent callers wait for a single
+// refresh and then share the new token.
+func (a *RedditAccount) accessToken(ctx context.Context) (*Token, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.Token == nil || a.Token.Token == "" {
+		return nil, ErrNoToken
+	}
+
+	if time.Now().Add(tokenRefreshMargin).After(a.Token.Expiry) {
+		if a.reloadToken() {
+			// another process sharing the store has already
+			// refreshed the token
+		} else if a.Token.RefreshToken != "" || a.Token.AppOnly() {
+			if err := a.refresh(ctx); err != nil {
+				return nil, fmt.Errorf("unable to refresh token: %w", err)
+			}
+			if err := a.saveToken(); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"error": err,
+				}).Warn("unable to save refreshed token")
+			}
+		} else if time.Now().After(a.Token.Expiry) {
+			return nil, ErrTokenExpired
+		}
+	}
+
+	// the token is replaced rather than modified on refresh, so the
+	// pointer is safe to use after unlocking
+	return a.Token, nil
+}
+
+// setToken sets a new token and saves it to the Store
+func (a *RedditAccount) setToken(token *Token) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Token = token
+	a.DeviceID = token.DeviceID
+	return a.saveToken()
+}
+
+// saveToken saves the current token to the Store, if there is
+// one. a.mu must be held by the caller.
+func (a *RedditAccount) saveToken() error {
+	if a.Store == nil {
+		return nil
+	}
+	if err := a.Store.Save(a.Token); err != nil {
+		return fmt.Errorf("unable to save token: %w", err)
+	}
+	return nil
+}
+
+// restoreToken sets the token from the Store if a usable one with the
+// given grant type is stored for this account. Any grant type is
+// accepted if grantType is blank. The device ID must match for the
+// installed client grant.
+func (a *RedditAccount) restoreToken(grantType, deviceID string) bool {
+	if a.Store == nil {
+		return false
+	}
+	token, err := a.Store.Load()
+	if err != nil || token == nil || !token.usable() || !a.owns(token) {
+		return false
+	}
+	if grantType != "" && token.GrantType != grantType {
+		return false
+	}
+	if grantType == GrantTypeInstalledClient && token.DeviceID != deviceID {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Token = token
+	a.DeviceID = token.DeviceID
+	return true
+}
+
+// owns reports whether a stored token can be used by this account.
+// Tokens issued to another user are rejected, so that accounts sharing
+// a store don't act as each other.
+func (a *RedditAccount) owns(token *Token) bool {
+	if token.AppOnly() || a.Username == "" {
+		return true
+	}
+	return strings.EqualFold(token.Username, a.Username)
+}
+
+// reloadToken replaces the current token with the stored one if the
+// stored one is different, still valid and was obtained the same way
+// as the current one. a.mu must be held by the caller.
+func (a *RedditAccount) reloadToken() bool {
+	if a.Store == nil {
+		return false
+	}
+	token, err := a.Store.Load()
+	if err != nil || token == nil || token.Token == a.Token.Token || !a.owns(token) {
+		return false
+	}
+	if token.GrantType != a.Token.GrantType || token.DeviceID != a.Token.DeviceID {
+		return false
+	}
+	if !time.Now().Add(tokenRefreshMargin).Before(token.Expiry) {
+		return false
+	}
+	a.Token = token
+	return true
+}
+
+// requestToken posts data to the access token endpoint and decodes
+// the new token
+func (a *RedditAccount) requestToken(ctx context.Context, data url.Values) (*Token, error) {
+	// get the URL for logging in
+	redditURL := a.API.GetRedditURL(RedditEndpointLogin)
+
+	// send request
+	resp, err := a.API.PostFormContext(ctx, redditURL, data)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
 	// check response code
-	if resp.StatusCode != 200 {
-		return errors.New(fmt.Sprintf("bad status code: %d", resp.StatusCode))
+	if err := responseError(resp); err != nil {
+		return nil, err
 	}
 
 	// decode response into new token
@@ -81,21 +427,25 @@ func (a *RedditAccount) PasswordLogin(password string) error {
 	decoder := json.NewDecoder(resp.Body)
 	err = decoder.Decode(&token)
 	if err != nil {
-		return errors.New(fmt.Sprintf("unable to decode json: %s", err))
+		return nil, errors.New(fmt.Sprintf("unable to decode json: %s", err))
 	}
 	if token.Error != "" {
-		return errors.New(fmt.Sprintf("reddit returned error: %s", token.Error))
+		return nil, &APIError{
+			Errors: []APIErrorDetail{{Code: ErrorCode(token.Error)}},
+		}
 	}
 	if token.Token == "" {
 		// JSON decoded but token is bad
-		return errors.New(fmt.Sprintf("blank token"))
+		return nil, errors.New(fmt.Sprintf("blank token"))
 	}
 
-	// calculate expiry time
+	// record how the token was obtained and calculate expiry time
+	token.GrantType = data.Get("grant_type")
+	token.DeviceID = data.Get("device_id")
+	if !token.AppOnly() {
+		token.Username = a.Username
+	}
 	token.Expiry = time.Now().Add(time.Duration(token.ExpiresIn))
 
-	// store token
-	a.Token = &token
-
-	return nil
+	return &token, nil
 }
@@ -0,0 +1,73 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestAutomaticRefresh(t *testing.T) {
+	s, _ := newFakeReddit(t)
+	defer s.Close()
+	s.AddAuthCode("code", "bot")
+
+	api := s.NewAPI("")
+	if err := api.Account.CodeLogin("code", "http://localhost/callback"); err != nil {
+		t.Fatal(err)
+	}
+	old := api.Account.Token.Token
+	refreshToken := api.Account.Token.RefreshToken
+	if refreshToken == "" {
+		t.Fatal("no refresh token issued")
+	}
+
+	// the token is refreshed before it expires
+	s.ExpireTokens()
+	api.Account.Token.Expiry = time.Now()
+	me, err := api.RequestMe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Username != "bot" {
+		t.Errorf("got username %q", me.Username)
+	}
+	if api.Account.Token.Token == old || api.Account.Token.RefreshToken != refreshToken {
+		t.Errorf("got token %+v after refreshing", api.Account.Token)
+	}
+}
+
+func TestAppOnlyRenewal(t *testing.T) {
+	s, _ := newFakeReddit(t)
+	defer s.Close()
+
+	api := s.NewAPI("")
+	if err := api.Account.ClientCredentialsLogin(); err != nil {
+		t.Fatal(err)
+	}
+	old := api.Account.Token.Token
+
+	// app-only tokens are requested again instead of refreshed
+	s.ExpireTokens()
+	api.Account.Token.Expiry = time.Now()
+	it := api.RequestSubredditListing("test", reddit.SortNew, "", nil)
+	for it.Next() {
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if api.Account.Token.Token == old {
+		t.Error("token not renewed")
+	}
+}
+
+func TestNoRefreshToken(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	// password logins aren't given a refresh token
+	if err := api.Account.Refresh(); !errors.Is(err, reddit.ErrNoRefreshToken) {
+		t.Errorf("got %v, want ErrNoRefreshToken", err)
+	}
+}
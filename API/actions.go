@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// vote directions
+const (
+	VoteUp   = 1
+	VoteNone = 0
+	VoteDown = -1
+)
+
+// maxHideBatch is the most things hidden or unhidden per request
+const maxHideBatch = 50
+
+// maxReportReasonLen is the longest report reason reddit will accept
+const maxReportReasonLen = 100
+
+// postAction sends a request to an endpoint that returns an empty
+// object on success
+func (api *RedditAPI) postAction(ctx context.Context, endpoint string, data url.Values, idempotent bool) error {
+	u := api.GetOauthURL(endpoint)
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, idempotent)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response BaseResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return err
+	}
+	return response.Error()
+}
+
+// Vote votes on a post or comment. dir is VoteUp, VoteDown or VoteNone
+// to clear a vote.
+func (api *RedditAPI) Vote(name Fullname, dir int) error {
+	return api.VoteContext(context.Background(), name, dir)
+}
+
+// VoteContext is like Vote but with a context
+func (api *RedditAPI) VoteContext(ctx context.Context, name Fullname, dir int) error {
+	if err := checkFullname(name, KindPost, KindComment); err != nil {
+		return err
+	}
+	if dir < VoteDown || dir > VoteUp {
+		return &ValidationError{Field: "dir", Message: fmt.Sprintf("%d is not a vote direction", dir)}
+	}
+
+	// construct post data
+	data := url.Values{
+		"id":  {string(name)},
+		"dir": {strconv.Itoa(dir)},
+	}
+
+	return api.postAction(ctx, OauthEndpointVote, data, true)
+}
+
+// Save saves a post or comment. category may be blank, and is only
+// used by premium accounts.
+func (api *RedditAPI) Save(name Fullname, category string) error {
+	return api.SaveContext(context.Background(), name, category)
+}
+
+// SaveContext is like Save but with a context
+func (api *RedditAPI) SaveContext(ctx context.Context, name Fullname, category string) error {
+	if err := checkFullname(name, KindPost, KindComment); err != nil {
+		return err
+	}
+
+	// construct post data
+	data := url.Values{
+		"id": {string(name)},
+	}
+	if category != "" {
+		data.Set("category", category)
+	}
+
+	return api.postAction(ctx, OauthEndpointSave, data, true)
+}
+
+// Unsave removes a post or comment from the saved items
+func (api *RedditAPI) Unsave(name Fullname) error {
+	return api.UnsaveContext(context.Background(), name)
+}
+
+// UnsaveContext is like Unsave but with a context
+func (api *RedditAPI) UnsaveContext(ctx context.Context, name Fullname) error {
+	if err := checkFullname(name, KindPost, KindComment); err != nil {
+		return err
+	}
+
+	// construct post data
+	data := url.Values{
+		"id": {string(name)},
+	}
+
+	return api.postAction(ctx, OauthEndpointUnsave, data, true)
+}
+
+// Hide hides posts from the user's listings. Large numbers of posts
+// are split across several requests.
+func (api *RedditAPI) Hide(names ...Fullname) error {
+	return api.HideContext(context.Background(), names...)
+}
+
+// HideContext is like Hide but with a context
+func (api *RedditAPI) HideContext(ctx context.Context, names ...Fullname) error {
+	return api.hide(ctx, OauthEndpointHide, names)
+}
+
+// Unhide unhides posts. Large numbers of posts are split across
+// several requests.
+func (api *RedditAPI) Unhide(names ...Fullname) error {
+	return api.UnhideContext(context.Background(), names...)
+}
+
+// UnhideContext is like Unhide but with a context
+func (api *RedditAPI) UnhideContext(ctx context.Context, names ...Fullname) error {
+	return api.hide(ctx, OauthEndpointUnhide, names)
+}
+
+// hide sends the posts to the hide or unhide endpoint in batches
+func (api *RedditAPI) hide(ctx context.Context, endpoint string, names []Fullname) error {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		if err := checkFullname(name, KindPost); err != nil {
+			return err
+		}
+		ids[i] = string(name)
+	}
+
+	for len(ids) > 0 {
+		n := len(ids)
+		if n > maxHideBatch {
+			n = maxHideBatch
+		}
+
+		// construct post data
+		data := url.Values{
+			"id": {strings.Join(ids[:n], ",")},
+		}
+
+		if err := api.postAction(ctx, endpoint, data, true); err != nil {
+			return err
+		}
+		ids = ids[n:]
+	}
+	return nil
+}
+
+// Report reports a post, comment or message to the subreddit's
+// moderators. reason is a free-form reason, ruleReason names the
+// subreddit rule broken and customText is the text of a custom report.
+// Any of them may be blank, but not all.
+func (api *RedditAPI) Report(name Fullname, reason, ruleReason, customText string) error {
+	return api.ReportContext(context.Background(), name, reason, ruleReason, customText)
+}
+
+// ReportContext is like Report but with a context
+func (api *RedditAPI) ReportContext(ctx context.Context, name Fullname, reason, ruleReason, customText string) error {
+	if err := checkFullname(name, KindPost, KindComment, KindMessage); err != nil {
+		return err
+	}
+	if reason == "" && ruleReason == "" && customText == "" {
+		return &ValidationError{Field: "reason", Message: "no reason given"}
+	}
+	u := api.GetOauthURL(OauthEndpointReport)
+
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"thing_id": {string(name)},
+	}
+	fields := []struct{ name, text string }{
+		{"reason", reason},
+		{"rule_reason", ruleReason},
+		{"custom_text", customText},
+	}
+	for _, field := range fields {
+		if field.text == "" {
+			continue
+		}
+		if len([]rune(field.text)) > maxReportReasonLen {
+			return &ValidationError{Field: field.name, Message: fmt.Sprintf("longer than %d characters", maxReportReasonLen)}
+		}
+		data.Set(field.name, field.text)
+	}
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response BaseJSONResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return err
+	}
+	return response.Error()
+}
+
+// RequestSaved returns an iterator over the posts and comments saved
+// by the account. opts may be nil.
+func (api *RedditAPI) RequestSaved(opts *ListingOptions) *ThingIterator {
+	return api.RequestSavedContext(context.Background(), opts)
+}
+
+// RequestSavedContext is like RequestSaved but with a context
+func (api *RedditAPI) RequestSavedContext(ctx context.Context, opts *ListingOptions) *ThingIterator {
+	return api.userListing(ctx, OauthEndpointUserSaved, opts)
+}
+
+// RequestHidden returns an iterator over the posts hidden by the
+// account. opts may be nil.
+func (api *RedditAPI) RequestHidden(opts *ListingOptions) *ThingIterator {
+	return api.RequestHiddenContext(context.Background(), opts)
+}
+
+// RequestHiddenContext is like RequestHidden but with a context
+func (api *RedditAPI) RequestHiddenContext(ctx context.Context, opts *ListingOptions) *ThingIterator {
+	return api.userListing(ctx, OauthEndpointUserHidden, opts)
+}
+
+// userListing returns an iterator over one of the account's own
+// listings. If the account has no username, it is looked up first.
+func (api *RedditAPI) userListing(ctx context.Context, endpoint string, opts *ListingOptions) *ThingIterator {
+	username := api.Account.Username
+	if username == "" {
+		me, err := api.RequestMeContext(ctx)
+		if err != nil {
+			it := api.newThingIterator(ctx, api.GetOauthURL(endpoint, ""), url.Values{}, opts)
+			it.err = err
+			return it
+		}
+		username = me.Username
+	}
+	return api.newThingIterator(ctx, api.GetOauthURL(endpoint, username), url.Values{}, opts)
+}
@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestVote(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "other", "title", "body")
+	name := reddit.Fullname(post.Fullname())
+
+	for _, v := range []struct {
+		dir   int
+		score int64
+	}{{reddit.VoteUp, 2}, {reddit.VoteDown, 0}, {reddit.VoteNone, 1}} {
+		if err := api.Vote(name, v.dir); err != nil {
+			t.Fatal(err)
+		}
+		if stored, _ := s.Post(post.ID); stored.Score != v.score {
+			t.Errorf("got score %d after voting %d, want %d", stored.Score, v.dir, v.score)
+		}
+	}
+
+	var validationErr *reddit.ValidationError
+	if err := api.Vote(name, 2); !errors.As(err, &validationErr) {
+		t.Errorf("got %v, want a *ValidationError", err)
+	}
+}
+
+func TestSave(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "other", "title", "body")
+	comment, _ := s.AddComment(post.Fullname(), "other", "comment")
+
+	if err := api.Save(reddit.Fullname(post.Fullname()), "reading"); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.Save(reddit.Fullname(comment.Fullname()), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// saved things come most recent first
+	saved := func() []reddit.Fullname {
+		it := api.RequestSaved(nil)
+		var names []reddit.Fullname
+		for it.Next() {
+			names = append(names, it.Thing().Fullname())
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+	if got := saved(); len(got) != 2 || got[0] != reddit.Fullname(comment.Fullname()) {
+		t.Errorf("got %v", got)
+	}
+
+	if err := api.Unsave(reddit.Fullname(post.Fullname())); err != nil {
+		t.Fatal(err)
+	}
+	if got := saved(); len(got) != 1 {
+		t.Errorf("got %v after unsaving", got)
+	}
+}
+
+func TestHide(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	// more than fit in one request
+	var names []reddit.Fullname
+	for i := 0; i < 120; i++ {
+		post := s.AddPost("test", "other", "title", "body")
+		names = append(names, reddit.Fullname(post.Fullname()))
+	}
+	if err := api.Hide(names...); err != nil {
+		t.Fatal(err)
+	}
+	it := api.RequestHidden(nil)
+	n := 0
+	for it.Next() {
+		if post, ok := it.Thing().Post(); !ok || !post.Hidden {
+			t.Fatalf("got %+v", it.Thing().Data)
+		}
+		n++
+	}
+	if it.Err() != nil || n != 120 {
+		t.Fatalf("got %d hidden posts and %v, want 120", n, it.Err())
+	}
+
+	if err := api.Unhide(names[:60]...); err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := s.User("bot"); len(user.Hidden) != 60 {
+		t.Errorf("got %d hidden posts after unhiding, want 60", len(user.Hidden))
+	}
+}
+
+func TestReport(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "other", "title", "body")
+	name := reddit.Fullname(post.Fullname())
+
+	if err := api.Report(name, "", "", "spam"); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := s.Post(post.ID); len(stored.Reports) != 1 || stored.Reports[0].Reason != "spam" {
+		t.Errorf("got reports %+v", stored.Reports)
+	}
+
+	var validationErr *reddit.ValidationError
+	if err := api.Report(name, "", "", ""); !errors.As(err, &validationErr) {
+		t.Errorf("got %v with no reason, want a *ValidationError", err)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,17 +19,37 @@ func decodeJSON(body io.Reader, p interface{}) error {
 	return err
 }
 
+// decodeResponse returns the error for an unsuccessful response, or
+// decodes the body of a successful one into p
+func decodeResponse(resp *http.Response, p interface{}) error {
+	if err := responseError(resp); err != nil {
+		return err
+	}
+	if err := decodeJSON(resp.Body, p); err != nil {
+		return err
+	}
+	if r, ok := p.(statusRecorder); ok {
+		r.recordStatus(resp.StatusCode)
+	}
+	return nil
+}
+
 // RequestMe queries the "me" API endpoint
 func (api *RedditAPI) RequestMe() (*MeResponse, error) {
-	url := GetOauthURL(OauthEndpointMe)
-	resp, err := api.Get(url, nil)
+	return api.RequestMeContext(context.Background())
+}
+
+// RequestMeContext is like RequestMe but with a context
+func (api *RedditAPI) RequestMeContext(ctx context.Context) (*MeResponse, error) {
+	url := api.GetOauthURL(OauthEndpointMe)
+	resp, err := api.GetContext(ctx, url, nil)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var response MeResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
+	if err := decodeResponse(resp, &response); err != nil {
 		return nil, err
 	}
 	if err := response.Error(); err != nil {
@@ -40,16 +61,21 @@ func (api *RedditAPI) RequestMe() (*MeResponse, error) {
 
 // RequestStylesheet gets the stylesheet of a particular subreddit
 func (api *RedditAPI) RequestStylesheet(subreddit string) (string, error) {
-	url := GetOauthURL(OauthEndpointStylesheet, subreddit)
-	resp, err := api.Get(url, nil)
+	return api.RequestStylesheetContext(context.Background(), subreddit)
+}
+
+// RequestStylesheetContext is like RequestStylesheet but with a context
+func (api *RedditAPI) RequestStylesheetContext(ctx context.Context, subreddit string) (string, error) {
+	url := api.GetOauthURL(OauthEndpointStylesheet, subreddit)
+	resp, err := api.GetContext(ctx, url, nil)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	// check for 200
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.New(fmt.Sprintf("returned status code %d", resp.StatusCode))
+	// check response code
+	if err := responseError(resp); err != nil {
+		return "", err
 	}
 
 	bodyBytes, err := ioutil.ReadAll(resp.Body)
@@ -65,10 +91,15 @@ func (api *RedditAPI) RequestStylesheet(subreddit string) (string, error) {
 // e.g. %% %% for images instead of actual urls) for a particular
 // subrededit
 func (api *RedditAPI) RequestStylesheetTemplate(subreddit string) (*StylesheetTemplateData, error) {
-	u := GetOauthURL(OauthEndpointStylesheetTemplate, subreddit)
+	return api.RequestStylesheetTemplateContext(context.Background(), subreddit)
+}
+
+// RequestStylesheetTemplateContext is like RequestStylesheetTemplate but with a context
+func (api *RedditAPI) RequestStylesheetTemplateContext(ctx context.Context, subreddit string) (*StylesheetTemplateData, error) {
+	u := api.GetOauthURL(OauthEndpointStylesheetTemplate, subreddit)
 
 	// send request
-	resp, err := api.Get(u, url.Values{
+	resp, err := api.GetContext(ctx, u, url.Values{
 		"raw_json": {"1"},
 	})
 	if err != nil {
@@ -78,7 +109,7 @@ func (api *RedditAPI) RequestStylesheetTemplate(subreddit string) (*StylesheetTe
 
 	// decode response
 	var response stylesheetTemplateIntermediaryResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
+	if err := decodeResponse(resp, &response); err != nil {
 		return nil, err
 	}
 	if err := response.Error(); err != nil {
@@ -96,7 +127,12 @@ func (api *RedditAPI) RequestStylesheetTemplate(subreddit string) (*StylesheetTe
 
 // RequestSetStylesheet sets the stylesheet for a subreddit
 func (api *RedditAPI) RequestSetStylesheet(subreddit, stylesheet, reason string) (*SetStylesheetResponse, error) {
-	u := GetOauthURL(OauthEndpointSetStylesheet, subreddit)
+	return api.RequestSetStylesheetContext(context.Background(), subreddit, stylesheet, reason)
+}
+
+// RequestSetStylesheetContext is like RequestSetStylesheet but with a context
+func (api *RedditAPI) RequestSetStylesheetContext(ctx context.Context, subreddit, stylesheet, reason string) (*SetStylesheetResponse, error) {
+	u := api.GetOauthURL(OauthEndpointSetStylesheet, subreddit)
 
 	// construct post data
 	data := url.Values{
@@ -107,14 +143,14 @@ func (api *RedditAPI) RequestSetStylesheet(subreddit, stylesheet, reason string)
 	}
 
 	// send request
-	resp, err := api.PostForm(u, data)
+	resp, err := api.postForm(ctx, u, data, true)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var response SetStylesheetResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
+	if err := decodeResponse(resp, &response); err != nil {
 		return nil, err
 	}
 	if err := response.Error(); err != nil {
@@ -131,62 +167,64 @@ func (api *RedditAPI) RequestSetStylesheet(subreddit, stylesheet, reason string)
 	return &response, nil
 }
 
+// RequestSubmitTextPost submits a text post to a subreddit
 func (api *RedditAPI) RequestSubmitTextPost(subreddit, title, text string, ad, nsfw, spoiler, sendReplies bool) (*SubmitPostData, error) {
-	// TODO: initial data validation
+	return api.RequestSubmitTextPostContext(context.Background(), subreddit, title, text, ad, nsfw, spoiler, sendReplies)
+}
 
-	u := GetOauthURL(OauthEndpointSubmitPost)
+// RequestSubmitTextPostContext is like RequestSubmitTextPost but with a context
+func (api *RedditAPI) RequestSubmitTextPostContext(ctx context.Context, subreddit, title, text string, ad, nsfw, spoiler, sendReplies bool) (*SubmitPostData, error) {
+	return api.RequestSubmitTextPostWithOptionsContext(ctx, subreddit, title, text, &SubmitOptions{
+		Ad:          ad,
+		NSFW:        nsfw,
+		Spoiler:     spoiler,
+		SendReplies: sendReplies,
+	})
+}
 
-	// construct post data
-	data := url.Values{
-		"api_type":    {"json"},
-		"kind":        {"self"},
-		"nsfw":        {"false"},
-		"resubmit":    {"false"},
-		"sendreplies": {"false"},
-		"spoiler":     {"false"},
-		"sr":          {subreddit},
-		"text":        {text},
-		"title":       {title},
-	}
+// RequestSubmitTextPostWithOptions submits a text post to a subreddit
+// with the given options. The text may be empty. opts may be nil.
+func (api *RedditAPI) RequestSubmitTextPostWithOptions(subreddit, title, text string, opts *SubmitOptions) (*SubmitPostData, error) {
+	return api.RequestSubmitTextPostWithOptionsContext(context.Background(), subreddit, title, text, opts)
+}
 
-	// send request
-	resp, err := api.PostForm(u, data)
-	if err != nil {
-		return nil, err
+// RequestSubmitTextPostWithOptionsContext is like
+// RequestSubmitTextPostWithOptions but with a context
+func (api *RedditAPI) RequestSubmitTextPostWithOptionsContext(ctx context.Context, subreddit, title, text string, opts *SubmitOptions) (*SubmitPostData, error) {
+	if len([]rune(text)) > maxSelfTextLen {
+		return nil, &ValidationError{Field: "text", Message: fmt.Sprintf("longer than %d characters", maxSelfTextLen)}
 	}
-	defer resp.Body.Close()
 
-	var response intermediateSubmitPostResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
-		return nil, err
-	}
-	if err := response.Error(); err != nil {
-		return nil, err
-	}
-	postData := response.GetData()
-	if postData.ID == "" {
-		return nil, errors.New("empty post ID")
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"kind":     {SubmitKindSelf},
+		"sr":       {subreddit},
+		"text":     {text},
+		"title":    {title},
 	}
 
-	// bodyBytes, err := ioutil.ReadAll(resp.Body)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// body := string(bodyBytes)
-	// logrus.Info(body)
-
-	return postData, nil
+	// send request
+	return api.submit(ctx, data, opts)
 }
 
 // RequestSticky allows setting a post to sticky
 // set num to -1 for bottom
-func (api *RedditAPI) RequestSticky(subreddit string, name string, state bool, num int) error {
-	u := GetOauthURL(OauthEndpointRequestSticky)
+func (api *RedditAPI) RequestSticky(subreddit string, name Fullname, state bool, num int) error {
+	return api.RequestStickyContext(context.Background(), subreddit, name, state, num)
+}
+
+// RequestStickyContext is like RequestSticky but with a context
+func (api *RedditAPI) RequestStickyContext(ctx context.Context, subreddit string, name Fullname, state bool, num int) error {
+	if err := checkFullname(name, KindPost); err != nil {
+		return err
+	}
+	u := api.GetOauthURL(OauthEndpointRequestSticky)
 
 	// construct post data
 	data := url.Values{
 		"api_type": {"json"},
-		"id":       {name},
+		"id":       {string(name)},
 		"r":        {subreddit},
 		//"to_profile": {"false"},
 	}
@@ -202,14 +240,14 @@ func (api *RedditAPI) RequestSticky(subreddit string, name string, state bool, n
 	}
 
 	// send request
-	resp, err := api.PostForm(u, data)
+	resp, err := api.postForm(ctx, u, data, false)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	var response requestStickyResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
+	if err := decodeResponse(resp, &response); err != nil {
 		return err
 	}
 	if err := response.Error(); err != nil {
@@ -227,13 +265,21 @@ func (api *RedditAPI) RequestSticky(subreddit string, name string, state bool, n
 }
 
 // RequestContestMode allows setting a post to contest mode
-func (api *RedditAPI) RequestContestMode(name string, state bool) error {
-	u := GetOauthURL(OauthEndpointRequestContestMode)
+func (api *RedditAPI) RequestContestMode(name Fullname, state bool) error {
+	return api.RequestContestModeContext(context.Background(), name, state)
+}
+
+// RequestContestModeContext is like RequestContestMode but with a context
+func (api *RedditAPI) RequestContestModeContext(ctx context.Context, name Fullname, state bool) error {
+	if err := checkFullname(name, KindPost); err != nil {
+		return err
+	}
+	u := api.GetOauthURL(OauthEndpointRequestContestMode)
 
 	// construct post data
 	data := url.Values{
 		"api_type": {"json"},
-		"id":       {name},
+		"id":       {string(name)},
 	}
 	if state {
 		// enable
@@ -244,14 +290,14 @@ func (api *RedditAPI) RequestContestMode(name string, state bool) error {
 	}
 
 	// send request
-	resp, err := api.PostForm(u, data)
+	resp, err := api.postForm(ctx, u, data, true)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	var response requestContestModeResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
+	if err := decodeResponse(resp, &response); err != nil {
 		return err
 	}
 	if err := response.Error(); err != nil {
@@ -270,6 +316,11 @@ func (api *RedditAPI) RequestContestMode(name string, state bool) error {
 
 // RequestPostJSON gets the JSON for a particular post
 func (api *RedditAPI) RequestPostJSON(u *url.URL) (*PostResponse, error) {
+	return api.RequestPostJSONContext(context.Background(), u)
+}
+
+// RequestPostJSONContext is like RequestPostJSON but with a context
+func (api *RedditAPI) RequestPostJSONContext(ctx context.Context, u *url.URL) (*PostResponse, error) {
 	path := u.Path
 	// remove trailing slash
 	for path[len(path)-1] == '/' {
@@ -279,14 +330,14 @@ func (api *RedditAPI) RequestPostJSON(u *url.URL) (*PostResponse, error) {
 	// add .json
 	path = path + ".json"
 
-	// change to oauth
-	u.Host = oauthHost
-
-	// reconstruct
-	u.Path = path
+	// change to oauth and reconstruct
+	base := api.oauthBaseURL()
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	u.Path = basePath(base) + path
 
 	// get json
-	resp, err := api.Get(u, url.Values{
+	resp, err := api.GetContext(ctx, u, url.Values{
 		"raw_json": {"1"},
 	})
 	if err != nil {
@@ -294,52 +345,58 @@ func (api *RedditAPI) RequestPostJSON(u *url.URL) (*PostResponse, error) {
 	}
 	defer resp.Body.Close()
 
-	// decode into arrays
-	var arrays []json.RawMessage
-	err = decodeJSON(resp.Body, &arrays)
+	// decode into the post listing and the comments listing
+	var listings []Thing
+	err = decodeResponse(resp, &listings)
 	if err != nil {
 		return nil, err
 	}
-
-	// decode the first item into a post
-	var posts postListingIntermediary
-	err = json.Unmarshal(arrays[0], &posts)
-	if err != nil {
-		return nil, err
+	if len(listings) < 2 {
+		return nil, errors.New("unexpected response for post")
 	}
-	post := posts.Data.Children[0].Data
-
-	// decode the second item into a comments listing
-	var commentsListing commentListingIntermediary
-	err = json.Unmarshal(arrays[1], &commentsListing)
-	if err != nil {
-		return nil, err
+	posts, ok := listings[0].Listing()
+	if !ok || len(posts.Children) == 0 {
+		return nil, errors.New(fmt.Sprintf("unexpected kind: %s", listings[0].Kind))
+	}
+	post, ok := posts.Children[0].Post()
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unexpected kind: %s", posts.Children[0].Kind))
+	}
+	commentsListing, ok := listings[1].Listing()
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unexpected kind: %s", listings[1].Kind))
 	}
 
 	// restructure the comments listing into an array of comments
+	replies, more := splitComments(commentsListing.Children)
 	var comments = []CommentResponse{}
-	for _, comment := range commentsListing.Data.Children {
-		err = comment.Data.DecodeReplies()
-		if err != nil {
-			return nil, err
-		}
-		comments = append(comments, comment.Data)
+	for _, comment := range replies {
+		comments = append(comments, *comment)
 	}
 
 	// store the comments in the post
 	post.Replies = comments
+	post.More = more
 
-	return &post, nil
+	return post, nil
 }
 
-// RequestRemovePost removes a post as a moderator. Spam specifies
-// whether or not to remove it as spam
-func (api *RedditAPI) RequestRemovePost(name string, spam bool) error {
-	u := GetOauthURL(OauthEndpointRequestRemovePost)
+// RequestRemovePost removes a post or comment as a moderator. Spam
+// specifies whether or not to remove it as spam
+func (api *RedditAPI) RequestRemovePost(name Fullname, spam bool) error {
+	return api.RequestRemovePostContext(context.Background(), name, spam)
+}
+
+// RequestRemovePostContext is like RequestRemovePost but with a context
+func (api *RedditAPI) RequestRemovePostContext(ctx context.Context, name Fullname, spam bool) error {
+	if err := checkFullname(name, KindPost, KindComment); err != nil {
+		return err
+	}
+	u := api.GetOauthURL(OauthEndpointRequestRemovePost)
 
 	// construct post data
 	data := url.Values{
-		"id": {name},
+		"id": {string(name)},
 	}
 	if spam {
 		data["spam"] = []string{"true"}
@@ -348,14 +405,14 @@ func (api *RedditAPI) RequestRemovePost(name string, spam bool) error {
 	}
 
 	// send request
-	resp, err := api.PostForm(u, data)
+	resp, err := api.postForm(ctx, u, data, false)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	var response requestRemovePostResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
+	if err := decodeResponse(resp, &response); err != nil {
 		return err
 	}
 	if err := response.Error(); err != nil {
@@ -374,7 +431,12 @@ func (api *RedditAPI) RequestRemovePost(name string, spam bool) error {
 
 // ComposeMessage sends a message to another user
 func (api *RedditAPI) ComposeMessage(to, subject, text string) error {
-	u := GetOauthURL(OauthEndpointComposeMessage)
+	return api.ComposeMessageContext(context.Background(), to, subject, text)
+}
+
+// ComposeMessageContext is like ComposeMessage but with a context
+func (api *RedditAPI) ComposeMessageContext(ctx context.Context, to, subject, text string) error {
+	u := api.GetOauthURL(OauthEndpointComposeMessage)
 
 	// construct post data
 	data := url.Values{
@@ -385,14 +447,14 @@ func (api *RedditAPI) ComposeMessage(to, subject, text string) error {
 	}
 
 	// send request
-	resp, err := api.PostForm(u, data)
+	resp, err := api.PostFormContext(ctx, u, data)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	var response requestRemovePostResponse
-	if err := decodeJSON(resp.Body, &response); err != nil {
+	var response ComposeMessageResponse
+	if err := decodeResponse(resp, &response); err != nil {
 		return err
 	}
 	if err := response.Error(); err != nil {
@@ -0,0 +1,30 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestComposeMessage(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	if err := api.ComposeMessage("other", "hello", "hi there"); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := s.User("other")
+	if len(other.Inbox) != 1 || other.Inbox[0].Body != "hi there" {
+		t.Errorf("got inbox %+v", other.Inbox)
+	}
+
+	err := api.ComposeMessage("nobody", "hello", "hi there")
+	if !errors.Is(err, reddit.ErrCodeUserDoesntExist) {
+		t.Errorf("got %v for a missing user", err)
+	}
+	err = api.ComposeMessage("other", "", "hi there")
+	if !errors.Is(err, reddit.ErrCodeNoSubject) {
+		t.Errorf("got %v for a missing subject", err)
+	}
+}
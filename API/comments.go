@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxMoreChildren is the most comment IDs reddit will expand in one
+// morechildren request
+const maxMoreChildren = 100
+
+// commentTree indexes a post's comments so that expanded comments can
+// be spliced in under the right parents
+type commentTree struct {
+	post     *PostResponse
+	comments map[Fullname]*CommentResponse
+	stubs    []*MoreChildren
+	// topLevel holds new top-level comments. They are only added
+	// to the post once splicing is finished, as appending to
+	// post.Replies would move the comments already indexed.
+	topLevel []*CommentResponse
+}
+
+// newCommentTree indexes the comments and stubs in a post
+func newCommentTree(post *PostResponse) *commentTree {
+	tree := &commentTree{
+		post:     post,
+		comments: map[Fullname]*CommentResponse{},
+	}
+	tree.stubs = append(tree.stubs, post.More...)
+	for i := range post.Replies {
+		tree.add(&post.Replies[i])
+	}
+	return tree
+}
+
+// add indexes a comment and its replies
+func (tree *commentTree) add(comment *CommentResponse) {
+	tree.comments[comment.Name] = comment
+	tree.stubs = append(tree.stubs, comment.More...)
+	for _, reply := range comment.Replies {
+		tree.add(reply)
+	}
+}
+
+// clearStubs removes every stub from the tree
+func (tree *commentTree) clearStubs() {
+	tree.post.More = nil
+	for _, comment := range tree.comments {
+		comment.More = nil
+	}
+}
+
+// splice adds comments and stubs to the tree under their parents
+func (tree *commentTree) splice(comments []*CommentResponse, stubs []*MoreChildren) {
+	// index everything first, as a reply may come before its parent
+	var added []*CommentResponse
+	for _, comment := range comments {
+		if _, ok := tree.comments[comment.Name]; ok {
+			// already in the tree
+			continue
+		}
+		tree.comments[comment.Name] = comment
+		added = append(added, comment)
+	}
+
+	for _, comment := range added {
+		if parent, ok := tree.comments[comment.ParentID]; ok {
+			parent.Replies = append(parent.Replies, comment)
+		} else {
+			tree.topLevel = append(tree.topLevel, comment)
+		}
+	}
+	for _, stub := range stubs {
+		if parent, ok := tree.comments[stub.ParentID]; ok {
+			parent.More = append(parent.More, stub)
+		} else {
+			tree.post.More = append(tree.post.More, stub)
+		}
+	}
+}
+
+// finish adds the new top-level comments to the post
+func (tree *commentTree) finish() {
+	for _, comment := range tree.topLevel {
+		tree.post.Replies = append(tree.post.Replies, *comment)
+	}
+	tree.topLevel = nil
+}
+
+// ExpandMoreComments replaces the "more" stubs in a post's comment
+// tree with the comments they stand for, fetching them with
+// /api/morechildren in batches and following "continue this thread"
+// links, until the whole thread has been loaded. Large threads may
+// take many requests, so limit is the most requests to send, or 0 for
+// no limit. Stubs that are left over, or that reddit can't expand, are
+// kept in the tree.
+func (api *RedditAPI) ExpandMoreComments(post *PostResponse, limit int) error {
+	return api.ExpandMoreCommentsContext(context.Background(), post, limit)
+}
+
+// ExpandMoreCommentsContext is like ExpandMoreComments but with a
+// context
+func (api *RedditAPI) ExpandMoreCommentsContext(ctx context.Context, post *PostResponse, limit int) error {
+	requests := 0
+	loaded := -1
+	for {
+		tree := newCommentTree(post)
+		if len(tree.stubs) == 0 || len(tree.comments) == loaded {
+			// finished, or the last round found nothing new
+			return nil
+		}
+		loaded = len(tree.comments)
+		queue := tree.stubs
+		tree.clearStubs()
+
+		for len(queue) > 0 {
+			if limit > 0 && requests >= limit {
+				// put back the stubs that weren't expanded
+				tree.splice(nil, queue)
+				tree.finish()
+				return nil
+			}
+			requests++
+
+			if len(queue[0].Children) == 0 {
+				// the thread is too deep, so load it from the
+				// parent comment
+				stub := queue[0]
+				queue = queue[1:]
+				if err := api.expandThread(ctx, tree, stub); err != nil {
+					tree.finish()
+					return err
+				}
+				continue
+			}
+
+			batch, rest := nextMoreChildren(queue)
+			queue = rest
+			comments, more, err := api.requestMoreChildren(ctx, post.Name, batch)
+			if err != nil {
+				tree.finish()
+				return err
+			}
+			tree.splice(comments, more)
+		}
+		tree.finish()
+	}
+}
+
+// nextMoreChildren takes up to maxMoreChildren comment IDs from the
+// stubs at the front of the queue, returning them and the rest of the
+// queue. A stub that doesn't fit is split.
+func nextMoreChildren(queue []*MoreChildren) ([]string, []*MoreChildren) {
+	var ids []string
+	for len(queue) > 0 && len(queue[0].Children) > 0 && len(ids) < maxMoreChildren {
+		stub := queue[0]
+		n := maxMoreChildren - len(ids)
+		if n >= len(stub.Children) {
+			ids = append(ids, stub.Children...)
+			queue = queue[1:]
+			continue
+		}
+		ids = append(ids, stub.Children[:n]...)
+		rest := *stub
+		rest.Children = stub.Children[n:]
+		queue = append([]*MoreChildren{&rest}, queue[1:]...)
+	}
+	return ids, queue
+}
+
+// requestMoreChildren fetches the comments with the given IDs
+func (api *RedditAPI) requestMoreChildren(ctx context.Context, linkID Fullname, ids []string) ([]*CommentResponse, []*MoreChildren, error) {
+	u := api.GetOauthURL(OauthEndpointMoreChildren)
+
+	resp, err := api.GetContext(ctx, u, url.Values{
+		"api_type":       {"json"},
+		"link_id":        {string(linkID)},
+		"children":       {strings.Join(ids, ",")},
+		"limit_children": {"false"},
+		"raw_json":       {"1"},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var response thingsResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return nil, nil, err
+	}
+	if err := response.Error(); err != nil {
+		return nil, nil, err
+	}
+
+	comments, more := splitComments(response.JSON.Data.Things)
+	return comments, more, nil
+}
+
+// expandThread loads the replies to the parent of a "continue this
+// thread" stub
+func (api *RedditAPI) expandThread(ctx context.Context, tree *commentTree, stub *MoreChildren) error {
+	parent, ok := tree.comments[stub.ParentID]
+	if !ok {
+		// nowhere to put the replies
+		return nil
+	}
+
+	u := api.GetOauthURL(OauthEndpointCommentThread, tree.post.Name.ID(), stub.ParentID.ID())
+
+	resp, err := api.GetContext(ctx, u, url.Values{
+		"raw_json": {"1"},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// the second item is a listing starting from the parent
+	var arrays []json.RawMessage
+	if err := decodeResponse(resp, &arrays); err != nil {
+		return err
+	}
+	if len(arrays) < 2 {
+		return errors.New(fmt.Sprintf("unexpected response for thread %s", stub.ParentID))
+	}
+	var thing Thing
+	if err := json.Unmarshal(arrays[1], &thing); err != nil {
+		return err
+	}
+	listing, ok := thing.Listing()
+	if !ok {
+		return errors.New(fmt.Sprintf("unexpected kind: %s", thing.Kind))
+	}
+	comments, _ := splitComments(listing.Children)
+
+	for _, comment := range comments {
+		if comment.Name != parent.Name {
+			continue
+		}
+		// the replies hang off the copy of the parent, so move
+		// them across
+		for _, reply := range comment.Replies {
+			if _, ok := tree.comments[reply.Name]; !ok {
+				parent.Replies = append(parent.Replies, reply)
+			}
+		}
+		parent.More = append(parent.More, comment.More...)
+	}
+	return nil
+}
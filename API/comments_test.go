@@ -0,0 +1,136 @@
+package api_test
+
+import (
+	"net/url"
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+	"github.com/joshbarrass/goreddit/fakereddit"
+)
+
+// countComments counts the comments in a tree, checking that each is
+// only seen once and that no stubs are left
+func countComments(t *testing.T, comments []*reddit.CommentResponse, seen map[reddit.Fullname]bool) int {
+	n := 0
+	for _, c := range comments {
+		if seen[c.Name] {
+			t.Errorf("%s appears twice", c.Name)
+		}
+		seen[c.Name] = true
+		if len(c.More) > 0 {
+			t.Errorf("%s still has a more stub", c.Name)
+		}
+		for _, reply := range c.Replies {
+			if reply.ParentID != c.Name {
+				t.Errorf("%s is under %s, but its parent is %s", reply.Name, c.Name, reply.ParentID)
+			}
+		}
+		n += 1 + countComments(t, c.Replies, seen)
+	}
+	return n
+}
+
+func TestExpandMoreComments(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	s.MoreCommentsThreshold = 2
+	s.MaxCommentDepth = 3
+
+	// a wide tree and a deep chain
+	post := s.AddPost("test", "other", "title", "body")
+	parents := []string{post.Fullname()}
+	for i := 0; i < 50; i++ {
+		c, _ := s.AddComment(parents[i*7%len(parents)], "other", "wide")
+		parents = append(parents, c.Fullname())
+	}
+	last := post.Fullname()
+	for i := 0; i < 10; i++ {
+		c, _ := s.AddComment(last, "other", "deep")
+		last = c.Fullname()
+	}
+
+	u, _ := url.Parse("https://www.reddit.com/comments/" + post.ID)
+	got, err := api.RequestPostJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.More) == 0 {
+		t.Fatal("no more stubs to expand")
+	}
+	if err := api.ExpandMoreComments(got, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.More) > 0 {
+		t.Errorf("%d stubs left on the post", len(got.More))
+	}
+	var top []*reddit.CommentResponse
+	for i := range got.Replies {
+		top = append(top, &got.Replies[i])
+	}
+	if n := countComments(t, top, map[reddit.Fullname]bool{}); n != 60 {
+		t.Errorf("got %d comments, want 60", n)
+	}
+}
+
+func TestExpandMoreCommentsLimit(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	s.MaxCommentDepth = 2
+
+	// each chain needs a request for its own thread
+	post := s.AddPost("test", "other", "title", "body")
+	for i := 0; i < 3; i++ {
+		c, _ := s.AddComment(post.Fullname(), "other", "comment")
+		reply, _ := s.AddComment(c.Fullname(), "other", "reply")
+		s.AddComment(reply.Fullname(), "other", "deep")
+	}
+
+	u, _ := url.Parse("https://www.reddit.com/comments/" + post.ID)
+	got, err := api.RequestPostJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.ExpandMoreComments(got, 1); err != nil {
+		t.Fatal(err)
+	}
+	expanded, stubs := 0, 0
+	for _, c := range got.Replies {
+		if len(c.Replies) != 1 {
+			t.Fatalf("got %d replies to %s", len(c.Replies), c.Name)
+		}
+		expanded += len(c.Replies[0].Replies)
+		stubs += len(c.Replies[0].More)
+	}
+	if expanded != 1 || stubs != 2 {
+		t.Errorf("got %d threads expanded and %d left, want 1 and 2", expanded, stubs)
+	}
+}
+
+func TestExpandMoreCommentsUnloadable(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	s.MoreCommentsThreshold = 1
+	post := s.AddPost("test", "other", "title", "body")
+	var last fakereddit.Comment
+	for i := 0; i < 5; i++ {
+		last, _ = s.AddComment(post.Fullname(), "other", "comment")
+	}
+	s.UnloadableComments = []string{last.ID}
+
+	// reddit keeps returning a stub for the comment, so expanding
+	// stops once a round finds nothing new
+	u, _ := url.Parse("https://www.reddit.com/comments/" + post.ID)
+	got, err := api.RequestPostJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.ExpandMoreComments(got, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Replies) != 4 {
+		t.Errorf("got %d comments, want 4", len(got.Replies))
+	}
+	if len(got.More) != 1 || len(got.More[0].Children) != 1 || got.More[0].Children[0] != last.ID {
+		t.Errorf("got stubs %+v", got.More)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/url"
+	"strings"
 )
 
 // schemes
@@ -20,12 +21,14 @@ const (
 	oauthHost   = "oauth.reddit.com"
 )
 
-// GetRedditURL returns the URL for a reddit endpoint
+// GetRedditURL returns the URL for a reddit endpoint on the default
+// host
 func GetRedditURL(endpoint string, formats ...interface{}) *url.URL {
 	return getURL(fmt.Sprintf(endpoint, formats...), "reddit")
 }
 
-// GetOauthURL returns the URL for an Oauth endpoint
+// GetOauthURL returns the URL for an Oauth endpoint on the default
+// host
 func GetOauthURL(endpoint string, formats ...interface{}) *url.URL {
 	return getURL(fmt.Sprintf(endpoint, formats...), "oauth")
 }
@@ -54,9 +57,83 @@ func getURL(endpoint, t string) *url.URL {
 	}
 }
 
+// GetRedditURL returns the URL for a reddit endpoint using the API's
+// RedditBaseURL
+func (api *RedditAPI) GetRedditURL(endpoint string, formats ...interface{}) *url.URL {
+	return joinURL(api.redditBaseURL(), fmt.Sprintf(endpoint, formats...))
+}
+
+// GetOauthURL returns the URL for an Oauth endpoint using the API's
+// OauthBaseURL
+func (api *RedditAPI) GetOauthURL(endpoint string, formats ...interface{}) *url.URL {
+	return joinURL(api.oauthBaseURL(), fmt.Sprintf(endpoint, formats...))
+}
+
+// redditBaseURL returns the base URL for reddit endpoints, falling
+// back to the default if it is not set
+func (api *RedditAPI) redditBaseURL() *url.URL {
+	if api.RedditBaseURL == nil {
+		return getURL("", "reddit")
+	}
+	return api.RedditBaseURL
+}
+
+// oauthBaseURL returns the base URL for Oauth endpoints, falling back
+// to the default if it is not set
+func (api *RedditAPI) oauthBaseURL() *url.URL {
+	if api.OauthBaseURL == nil {
+		return getURL("", "oauth")
+	}
+	return api.OauthBaseURL
+}
+
+// joinURL returns a copy of base with the endpoint appended to its
+// path
+func joinURL(base *url.URL, endpoint string) *url.URL {
+	return &url.URL{
+		Scheme: base.Scheme,
+		Host:   base.Host,
+		Path:   basePath(base) + endpoint,
+	}
+}
+
+// basePath returns the path prefix of a base URL without a trailing
+// slash
+func basePath(base *url.URL) string {
+	return strings.TrimSuffix(base.Path, "/")
+}
+
+// matchBaseURL returns the length of the base URL's path prefix if u
+// is under base, or -1 if it isn't
+func matchBaseURL(base, u *url.URL) int {
+	if u.Scheme != base.Scheme || u.Host != base.Host {
+		return -1
+	}
+	prefix := basePath(base)
+	if u.Path != prefix && !strings.HasPrefix(u.Path, prefix+"/") {
+		return -1
+	}
+	return len(prefix)
+}
+
+// isAuthEndpoint reports whether u is one of the reddit endpoints used
+// to obtain tokens
+func (api *RedditAPI) isAuthEndpoint(u *url.URL) bool {
+	base := api.redditBaseURL()
+	if matchBaseURL(base, u) < 0 {
+		return false
+	}
+	switch strings.TrimPrefix(u.Path, basePath(base)) {
+	case RedditEndpointLogin, RedditEndpointAuthorize:
+		return true
+	}
+	return false
+}
+
 /* Reddit Endpoints */
 const (
-	RedditEndpointLogin = "/api/v1/access_token"
+	RedditEndpointLogin     = "/api/v1/access_token"
+	RedditEndpointAuthorize = "/api/v1/authorize"
 )
 
 /* Oauth Endpoints */
@@ -66,8 +143,49 @@ const (
 	OauthEndpointSetStylesheet      = "/r/%s/api/subreddit_stylesheet"
 	OauthEndpointStylesheetTemplate = "/r/%s/about/stylesheet.json"
 	OauthEndpointSubmitPost         = "/api/submit"
+	OauthEndpointSubmitGalleryPost  = "/api/submit_gallery_post.json"
+	OauthEndpointSubmitPollPost     = "/api/submit_poll_post.json"
+	OauthEndpointMediaAsset         = "/api/media/asset.json"
 	OauthEndpointRequestSticky      = "/api/set_subreddit_sticky"
 	OauthEndpointRequestContestMode = "/api/set_contest_mode"
 	OauthEndpointRequestRemovePost  = "/api/remove"
+	OauthEndpointRemovalReasons     = "/api/v1/modactions/removal_reasons"
+	OauthEndpointApprove            = "/api/approve"
+	OauthEndpointIgnoreReports      = "/api/ignore_reports"
+	OauthEndpointUnignoreReports    = "/api/unignore_reports"
+	OauthEndpointLock               = "/api/lock"
+	OauthEndpointUnlock             = "/api/unlock"
+	OauthEndpointMarkNSFW           = "/api/marknsfw"
+	OauthEndpointUnmarkNSFW         = "/api/unmarknsfw"
+	OauthEndpointSpoiler            = "/api/spoiler"
+	OauthEndpointUnspoiler          = "/api/unspoiler"
+	OauthEndpointDistinguish        = "/api/distinguish"
+	OauthEndpointSetSuggestedSort   = "/api/set_suggested_sort"
 	OauthEndpointComposeMessage     = "/api/compose"
+	OauthEndpointComment            = "/api/comment"
+	OauthEndpointEditUserText       = "/api/editusertext"
+	OauthEndpointDelete             = "/api/del"
+	OauthEndpointVote               = "/api/vote"
+	OauthEndpointSave               = "/api/save"
+	OauthEndpointUnsave             = "/api/unsave"
+	OauthEndpointHide               = "/api/hide"
+	OauthEndpointUnhide             = "/api/unhide"
+	OauthEndpointReport             = "/api/report"
+	OauthEndpointUserSaved          = "/user/%s/saved"
+	OauthEndpointUserHidden         = "/user/%s/hidden"
+	OauthEndpointSubredditListing   = "/r/%s/%s"
+	OauthEndpointSubredditComments  = "/r/%s/comments"
+	OauthEndpointModListing         = "/r/%s/about/%s"
+	OauthEndpointModLog             = "/r/%s/about/log"
+	OauthEndpointFriend             = "/r/%s/api/friend"
+	OauthEndpointUnfriend           = "/r/%s/api/unfriend"
+	OauthEndpointSetPermissions     = "/r/%s/api/setpermissions"
+	OauthEndpointAcceptModInvite    = "/r/%s/api/accept_moderator_invite"
+	OauthEndpointModmail            = "/api/mod/conversations"
+	OauthEndpointModmailThread      = "/api/mod/conversations/%s"
+	OauthEndpointModmailAction      = "/api/mod/conversations/%s/%s"
+	OauthEndpointModmailRead        = "/api/mod/conversations/read"
+	OauthEndpointModmailUnread      = "/api/mod/conversations/unread"
+	OauthEndpointMoreChildren       = "/api/morechildren"
+	OauthEndpointCommentThread      = "/comments/%s/_/%s"
 )
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// newBaseURLServer starts a server for the token and me endpoints,
+// checking that each uses the right kind of auth
+func newBaseURLServer(t *testing.T, oauthPrefix string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RedditEndpointLogin, func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "id" || secret != "secret" {
+			t.Errorf("token request without basic auth: %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"access_token":"a1","expires_in":3600}`))
+	})
+	mux.HandleFunc(oauthPrefix+OauthEndpointMe, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "bearer a1" {
+			t.Errorf("API request without bearer auth: %q", got)
+		}
+		w.Write([]byte(`{"name":"bot"}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestBaseURLPrefix(t *testing.T) {
+	srv := newBaseURLServer(t, "/oauth")
+	defer srv.Close()
+	api := NewRedditAPI("id", "secret", "test", "bot", false)
+	base, _ := url.Parse(srv.URL)
+	api.RedditBaseURL = base
+	api.OauthBaseURL, _ = url.Parse(srv.URL + "/oauth/")
+
+	if err := api.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.RequestMe(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSharedBaseURL(t *testing.T) {
+	srv := newBaseURLServer(t, "")
+	defer srv.Close()
+	api := NewRedditAPI("id", "secret", "test", "bot", false)
+	base, _ := url.Parse(srv.URL)
+	api.RedditBaseURL = base
+	api.OauthBaseURL = base
+
+	if err := api.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	me, err := api.RequestMe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Username != "bot" {
+		t.Errorf("got username %q", me.Username)
+	}
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errors returned by the API
+var (
+	// ErrNoToken is returned when a request needs a token but the
+	// account has not logged in
+	ErrNoToken = errors.New("no valid token")
+	// ErrTokenExpired is returned when the token has expired and
+	// cannot be refreshed
+	ErrTokenExpired = errors.New("token expired")
+	// ErrNoRefreshToken is returned when refreshing a token that
+	// was not issued with a refresh token
+	ErrNoRefreshToken = errors.New("no refresh token")
+	// ErrNoStoredToken is returned by RestoreToken when there is
+	// no usable token in the store
+	ErrNoStoredToken = errors.New("no usable stored token")
+	// ErrAppOnlyToken is returned when a request that acts on
+	// behalf of a user is attempted with an application-only token
+	ErrAppOnlyToken = errors.New("endpoint requires a user account but an application-only token is in use")
+	// ErrInvalidFullname is returned when a fullname is malformed
+	// or is the wrong kind for the request
+	ErrInvalidFullname = errors.New("invalid fullname")
+)
+
+// ErrorCode is an error code returned by reddit. An *APIError matches
+// an ErrorCode with errors.Is if it contains that code.
+type ErrorCode string
+
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
+// common error codes
+const (
+	ErrCodeRateLimit           ErrorCode = "RATELIMIT"
+	ErrCodeSubredditNoExist    ErrorCode = "SUBREDDIT_NOEXIST"
+	ErrCodeSubredditNotAllowed ErrorCode = "SUBREDDIT_NOTALLOWED"
+	ErrCodeAlreadySub          ErrorCode = "ALREADY_SUB"
+	ErrCodeNoText              ErrorCode = "NO_TEXT"
+	ErrCodeNoURL               ErrorCode = "NO_URL"
+	ErrCodeBadURL              ErrorCode = "BAD_URL"
+	ErrCodeTooLong             ErrorCode = "TOO_LONG"
+	ErrCodeNoSubject           ErrorCode = "NO_SUBJECT"
+	ErrCodeUserDoesntExist     ErrorCode = "USER_DOESNT_EXIST"
+	ErrCodeNoThingID           ErrorCode = "NO_THING_ID"
+	ErrCodeInvalidOption       ErrorCode = "INVALID_OPTION"
+	ErrCodeBadCSS              ErrorCode = "BAD_CSS"
+	ErrCodeThreadLocked        ErrorCode = "THREAD_LOCKED"
+	ErrCodeNotAuthor           ErrorCode = "NOT_AUTHOR"
+	ErrCodeDeletedComment      ErrorCode = "DELETED_COMMENT"
+	ErrCodeTooOld              ErrorCode = "TOO_OLD"
+	ErrCodeCantRestrictMod     ErrorCode = "CANT_RESTRICT_MODERATOR"
+	ErrCodeAlreadyModerator    ErrorCode = "ALREADY_MODERATOR"
+	ErrCodeNoInviteFound       ErrorCode = "NO_INVITE_FOUND"
+	ErrCodeInvalidGrant        ErrorCode = "invalid_grant"
+)
+
+// APIErrorDetail is a single error reported by reddit
+type APIErrorDetail struct {
+	Code    ErrorCode
+	Message string
+	// Field is the request field the error relates to, if any
+	Field string
+}
+
+func (d *APIErrorDetail) String() string {
+	s := string(d.Code)
+	if d.Message != "" {
+		s += ": " + d.Message
+	}
+	if d.Field != "" {
+		s += fmt.Sprintf(" (%s)", d.Field)
+	}
+	return s
+}
+
+// APIError is returned when reddit reports one or more errors
+// relating to a request
+type APIError struct {
+	Errors []APIErrorDetail
+	// RateLimit is the time until the rate limit resets, if reddit
+	// gave one
+	RateLimit time.Duration
+}
+
+func (e *APIError) Error() string {
+	details := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		details[i] = e.Errors[i].String()
+	}
+	return "reddit error: " + strings.Join(details, "; ")
+}
+
+// Has reports whether the error contains the given code
+func (e *APIError) Has(code ErrorCode) bool {
+	for _, d := range e.Errors {
+		if d.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Is allows errors.Is to match an ErrorCode contained in the error
+func (e *APIError) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && e.Has(code)
+}
+
+// As allows errors.As to convert a RATELIMIT error to a
+// *RateLimitError
+func (e *APIError) As(target interface{}) bool {
+	t, ok := target.(**RateLimitError)
+	if !ok {
+		return false
+	}
+	for _, d := range e.Errors {
+		if d.Code != ErrCodeRateLimit {
+			continue
+		}
+		delay := e.RateLimit
+		if delay == 0 {
+			delay = parseRateLimitMessage(d.Message)
+		}
+		*t = &RateLimitError{Reset: time.Now().Add(delay)}
+		return true
+	}
+	return false
+}
+
+// newAPIError creates an APIError from reddit's [code, message,
+// field] triples, returning nil if there are none
+func newAPIError(errs [][]string, rateLimit float64) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	e := &APIError{
+		RateLimit: time.Duration(rateLimit * float64(time.Second)),
+	}
+	for _, triple := range errs {
+		var d APIErrorDetail
+		if len(triple) > 0 {
+			d.Code = ErrorCode(triple[0])
+		}
+		if len(triple) > 1 {
+			d.Message = triple[1]
+		}
+		if len(triple) > 2 {
+			d.Field = triple[2]
+		}
+		e.Errors = append(e.Errors, d)
+	}
+	return e
+}
+
+// rateLimitMessageRegexp matches the delay in messages like "you are
+// doing that too much. try again in 5 minutes."
+var rateLimitMessageRegexp = regexp.MustCompile(`(\d+) (second|minute|hour)`)
+
+// parseRateLimitMessage gets the delay from a RATELIMIT message
+func parseRateLimitMessage(message string) time.Duration {
+	match := rateLimitMessageRegexp.FindStringSubmatch(message)
+	if match == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(match[1])
+	switch match[2] {
+	case "second":
+		return time.Duration(n) * time.Second
+	case "minute":
+		return time.Duration(n) * time.Minute
+	default:
+		return time.Duration(n) * time.Hour
+	}
+}
+
+// HTTPStatusError is returned when reddit responds with an
+// unsuccessful status code
+type HTTPStatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *HTTPStatusError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("reddit returned status %d: %s", e.StatusCode, message)
+}
+
+// responseError returns the error for a response with an unsuccessful
+// status, or nil. A 429 that outlasted the retries becomes a
+// *RateLimitError.
+func responseError(resp *http.Response) error {
+	if resp.StatusCode < 400 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &RateLimitError{Reset: rateLimitReset(resp.Header)}
+	}
+
+	// use reddit's message if it sent one
+	var body BaseResponse
+	decodeJSON(resp.Body, &body)
+	return &HTTPStatusError{StatusCode: resp.StatusCode, Message: body.Message}
+}
+
+// statusError returns an *HTTPStatusError for reddit's error and
+// message fields, taking the status from the response if the error
+// field is unset. It returns nil if neither field is set.
+func statusError(code int64, status int, message string) error {
+	if code == 0 && message == "" {
+		return nil
+	}
+	if code != 0 {
+		status = int(code)
+	}
+	return &HTTPStatusError{StatusCode: status, Message: message}
+}
+
+// ValidationError is returned when a request is rejected before being
+// sent because one of its fields is invalid
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
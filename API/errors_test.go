@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newTestAPI starts a server that issues tokens and passes every other
+// request to handler, and returns an API logged in to it. Requests are
+// not retried. Close the server when done.
+func newTestAPI(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *RedditAPI) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(RedditEndpointLogin, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"a1","expires_in":3600}`))
+	})
+	mux.HandleFunc("/", handler)
+	srv := httptest.NewServer(mux)
+
+	api := NewRedditAPI("id", "secret", "test", "bot", false)
+	api.RetryPolicy = RetryPolicy{}
+	base, _ := url.Parse(srv.URL)
+	api.RedditBaseURL = base
+	api.OauthBaseURL = base
+	if err := api.Account.PasswordLogin("pw"); err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return srv, api
+}
+
+func TestStatusError(t *testing.T) {
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "Forbidden", "error": 403}`))
+	})
+	defer srv.Close()
+
+	_, err := api.RequestSubmitTextPost("test", "title", "body", false, false, false, true)
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got %v, want an *HTTPStatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusForbidden || statusErr.Message != "Forbidden" {
+		t.Errorf("got %+v", statusErr)
+	}
+}
+
+func TestStatusErrorWithoutBody(t *testing.T) {
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	defer srv.Close()
+
+	err := api.ComposeMessage("other", "subject", "text")
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("got %v, want a 404 *HTTPStatusError", err)
+	}
+}
+
+func TestMessageWithoutErrorCode(t *testing.T) {
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": "something went wrong"}`))
+	})
+	defer srv.Close()
+
+	err := api.ComposeMessage("other", "subject", "text")
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("got %v, want an *HTTPStatusError", err)
+	}
+	if statusErr.StatusCode != http.StatusOK || statusErr.Message != "something went wrong" {
+		t.Errorf("got %+v", statusErr)
+	}
+}
+
+func TestTooManyRequests(t *testing.T) {
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	defer srv.Close()
+
+	_, err := api.RequestMe()
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("got %v, want a *RateLimitError", err)
+	}
+	if wait := time.Until(rateErr.Reset); wait < 110*time.Second || wait > 120*time.Second {
+		t.Errorf("got reset in %s, want about 2 minutes", wait)
+	}
+}
+
+func TestStylesheetStatusError(t *testing.T) {
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "Forbidden", "error": 403}`))
+	})
+	defer srv.Close()
+
+	_, err := api.RequestStylesheet("test")
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden || statusErr.Message != "Forbidden" {
+		t.Errorf("got %v, want a 403 *HTTPStatusError with reddit's message", err)
+	}
+}
+
+func TestTokenTooManyRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	api := newStoreAPI(srv, "bot", nil)
+	api.RetryPolicy = RetryPolicy{}
+	err := api.Account.PasswordLogin("pw")
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) || rateErr.Reset.IsZero() {
+		t.Errorf("got %v, want a *RateLimitError", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"json": {"errors": [["RATELIMIT", "you are doing that too much. try again in 5 minutes.", "ratelimit"], ["NO_TEXT", "we need something here", "title"]]}}`))
+	})
+	defer srv.Close()
+
+	_, err := api.RequestSubmitTextPost("test", "title", "body", false, false, false, true)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Errors) != 2 {
+		t.Fatalf("got %v, want an *APIError with two errors", err)
+	}
+	if !errors.Is(err, ErrCodeNoText) || errors.Is(err, ErrCodeBadURL) {
+		t.Errorf("errors.Is matched the wrong codes for %v", err)
+	}
+	if apiErr.Errors[1].Field != "title" {
+		t.Errorf("got field %q", apiErr.Errors[1].Field)
+	}
+
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatal("RATELIMIT did not convert to a *RateLimitError")
+	}
+	if wait := time.Until(rateErr.Reset); wait < 4*time.Minute || wait > 5*time.Minute {
+		t.Errorf("got reset in %s, want about 5 minutes", wait)
+	}
+}
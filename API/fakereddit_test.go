@@ -0,0 +1,28 @@
+package api_test
+
+import (
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+	"github.com/joshbarrass/goreddit/fakereddit"
+)
+
+// newFakeReddit starts a fake reddit with the users bot and other,
+// and the subreddit test moderated by bot. The returned API is logged
+// in as bot. Close the server when done.
+func newFakeReddit(t *testing.T) (*fakereddit.Server, *reddit.RedditAPI) {
+	s := fakereddit.NewServer("id", "secret")
+	s.AddUser("bot", "pw")
+	s.AddUser("other", "pw")
+	s.AddSubreddit("test", "bot")
+	return s, login(t, s, "bot")
+}
+
+// login returns an API logged in to the fake reddit as the user
+func login(t *testing.T, s *fakereddit.Server, username string) *reddit.RedditAPI {
+	api := s.NewAPI(username)
+	if err := api.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Fullname identifies a thing on reddit by combining its kind and
+// its base36 ID, e.g. t3_15bfi0
+type Fullname string
+
+// NewFullname creates a fullname from a kind and a base36 ID
+func NewFullname(kind, id string) (Fullname, error) {
+	if !isFullnameKind(kind) {
+		return "", fmt.Errorf("%w: unknown kind %q", ErrInvalidFullname, kind)
+	}
+	id = strings.ToLower(id)
+	if !isBase36(id) {
+		return "", fmt.Errorf("%w: invalid ID %q", ErrInvalidFullname, id)
+	}
+	return Fullname(kind + "_" + id), nil
+}
+
+// FullnameFromInt creates a fullname from a kind and a numeric ID
+func FullnameFromInt(kind string, id int64) (Fullname, error) {
+	if id < 0 {
+		return "", fmt.Errorf("%w: negative ID %d", ErrInvalidFullname, id)
+	}
+	return NewFullname(kind, strconv.FormatInt(id, 36))
+}
+
+// ParseFullname parses and validates a fullname
+func ParseFullname(s string) (Fullname, error) {
+	parts := strings.SplitN(s, "_", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("%w: %q", ErrInvalidFullname, s)
+	}
+	return NewFullname(parts[0], parts[1])
+}
+
+// FullnameFromURL gets the fullname of the post or comment a
+// permalink points to. Both reddit.com permalinks and redd.it short
+// links are understood.
+func FullnameFromURL(u *url.URL) (Fullname, error) {
+	host := strings.ToLower(u.Hostname())
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	// redd.it/{post}
+	if host == "redd.it" {
+		if len(segments) != 1 {
+			return "", fmt.Errorf("%w: not a short link: %s", ErrInvalidFullname, u)
+		}
+		return NewFullname(KindPost, segments[0])
+	}
+	if host != "reddit.com" && !strings.HasSuffix(host, ".reddit.com") {
+		return "", fmt.Errorf("%w: not a reddit URL: %s", ErrInvalidFullname, u)
+	}
+
+	// [/r/{subreddit}]/comments/{post}[/{slug}[/{comment}]]
+	if len(segments) >= 2 && (segments[0] == "r" || segments[0] == "u" || segments[0] == "user") {
+		segments = segments[2:]
+	}
+	if len(segments) < 2 || segments[0] != "comments" {
+		return "", fmt.Errorf("%w: not a permalink: %s", ErrInvalidFullname, u)
+	}
+	if len(segments) >= 4 {
+		return NewFullname(KindComment, segments[3])
+	}
+	return NewFullname(KindPost, segments[1])
+}
+
+// String returns the fullname as a string
+func (f Fullname) String() string {
+	return string(f)
+}
+
+// Kind returns the kind prefix, e.g. t3
+func (f Fullname) Kind() string {
+	i := strings.Index(string(f), "_")
+	if i < 0 {
+		return ""
+	}
+	return string(f[:i])
+}
+
+// ID returns the base36 ID without the kind prefix
+func (f Fullname) ID() string {
+	i := strings.Index(string(f), "_")
+	if i < 0 {
+		return string(f)
+	}
+	return string(f[i+1:])
+}
+
+// IDInt returns the numeric value of the base36 ID
+func (f Fullname) IDInt() (int64, error) {
+	return strconv.ParseInt(f.ID(), 36, 64)
+}
+
+// Validate returns an error wrapping ErrInvalidFullname if the
+// fullname is malformed
+func (f Fullname) Validate() error {
+	_, err := ParseFullname(string(f))
+	return err
+}
+
+// IsComment returns true if the fullname is for a comment
+func (f Fullname) IsComment() bool { return f.Kind() == KindComment }
+
+// IsAccount returns true if the fullname is for an account
+func (f Fullname) IsAccount() bool { return f.Kind() == KindAccount }
+
+// IsPost returns true if the fullname is for a post
+func (f Fullname) IsPost() bool { return f.Kind() == KindPost }
+
+// IsMessage returns true if the fullname is for a message
+func (f Fullname) IsMessage() bool { return f.Kind() == KindMessage }
+
+// IsSubreddit returns true if the fullname is for a subreddit
+func (f Fullname) IsSubreddit() bool { return f.Kind() == KindSubreddit }
+
+// IsAward returns true if the fullname is for an award
+func (f Fullname) IsAward() bool { return f.Kind() == KindAward }
+
+// checkFullname validates a fullname passed to an API method,
+// ensuring it is one of the given kinds
+func checkFullname(f Fullname, kinds ...string) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	for _, kind := range kinds {
+		if f.Kind() == kind {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not a %s", ErrInvalidFullname, f, strings.Join(kinds, " or "))
+}
+
+// isFullnameKind returns true for the kinds that have fullnames
+func isFullnameKind(kind string) bool {
+	switch kind {
+	case KindComment, KindAccount, KindPost, KindMessage, KindSubreddit, KindAward:
+		return true
+	}
+	return false
+}
+
+// isBase36 returns true if s is a non-empty lowercase base36 string
+func isBase36(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
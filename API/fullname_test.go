@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParseFullname(t *testing.T) {
+	valid := map[string]Fullname{
+		"t3_15bfi0": "t3_15bfi0",
+		"t1_ABC":    "t1_abc",
+		"t5_2qh1i":  "t5_2qh1i",
+	}
+	for s, want := range valid {
+		got, err := ParseFullname(s)
+		if err != nil || got != want {
+			t.Errorf("ParseFullname(%q) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "t3", "t3_", "t9_abc", "15bfi0", "t3_15b-fi0", "_abc"} {
+		if _, err := ParseFullname(s); !errors.Is(err, ErrInvalidFullname) {
+			t.Errorf("ParseFullname(%q) = %v, want ErrInvalidFullname", s, err)
+		}
+	}
+}
+
+func TestFullnameParts(t *testing.T) {
+	f := Fullname("t3_15bfi0")
+	if f.Kind() != KindPost || f.ID() != "15bfi0" || !f.IsPost() || f.IsComment() {
+		t.Errorf("got kind %q and ID %q", f.Kind(), f.ID())
+	}
+	n, err := f.IDInt()
+	if err != nil || n != 69397560 {
+		t.Errorf("got %d, %v", n, err)
+	}
+	if back, err := FullnameFromInt(KindPost, n); err != nil || back != f {
+		t.Errorf("got %q, %v converting back", back, err)
+	}
+	if _, err := FullnameFromInt(KindPost, -1); !errors.Is(err, ErrInvalidFullname) {
+		t.Errorf("got %v for a negative ID", err)
+	}
+}
+
+func TestFullnameFromURL(t *testing.T) {
+	valid := map[string]Fullname{
+		"https://www.reddit.com/r/golang/comments/15bfi0/some_title/":         "t3_15bfi0",
+		"https://old.reddit.com/r/golang/comments/15bfi0/some_title/jtq7x2k/": "t1_jtq7x2k",
+		"https://reddit.com/comments/15bfi0":                                  "t3_15bfi0",
+		"https://www.reddit.com/user/bot/comments/15bfi0/title/":              "t3_15bfi0",
+		"https://redd.it/15bfi0":                                              "t3_15bfi0",
+	}
+	for s, want := range valid {
+		u, _ := url.Parse(s)
+		got, err := FullnameFromURL(u)
+		if err != nil || got != want {
+			t.Errorf("FullnameFromURL(%s) = %q, %v, want %q", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{
+		"https://example.com/r/golang/comments/15bfi0/",
+		"https://notreddit.com/comments/15bfi0",
+		"https://www.reddit.com/r/golang/",
+		"https://redd.it/",
+	} {
+		u, _ := url.Parse(s)
+		if _, err := FullnameFromURL(u); !errors.Is(err, ErrInvalidFullname) {
+			t.Errorf("FullnameFromURL(%s) = %v, want ErrInvalidFullname", s, err)
+		}
+	}
+}
+
+func TestCheckFullname(t *testing.T) {
+	if err := checkFullname("t1_abc", KindPost, KindComment); err != nil {
+		t.Error(err)
+	}
+	if err := checkFullname("t5_abc", KindPost, KindComment); !errors.Is(err, ErrInvalidFullname) {
+		t.Errorf("got %v for a subreddit", err)
+	}
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// listing sorts
+const (
+	SortHot           = "hot"
+	SortNew           = "new"
+	SortTop           = "top"
+	SortRising        = "rising"
+	SortControversial = "controversial"
+)
+
+// time filters for the top and controversial sorts
+const (
+	TimeHour  = "hour"
+	TimeDay   = "day"
+	TimeWeek  = "week"
+	TimeMonth = "month"
+	TimeYear  = "year"
+	TimeAll   = "all"
+)
+
+// maxListingLimit is the most items reddit will return in one page
+const maxListingLimit = 100
+
+// ListingOptions controls the pagination of a listing
+type ListingOptions struct {
+	// Limit is the number of items to fetch per page, up to 100. If
+	// zero, reddit's default of 25 is used.
+	Limit int
+	// Max is the total number of items to return. If zero, the
+	// listing is followed until it is exhausted.
+	Max int
+	// Count is the number of items already seen in the listing
+	Count int
+	// After and Before are the fullnames to start after or
+	// before. If Before is set, the listing is followed backwards.
+	After  Fullname
+	Before Fullname
+}
+
+// ThingIterator iterates over the things in a listing, fetching more
+// pages as needed. Call Next before each Thing, and check Err once
+// Next returns false.
+type ThingIterator struct {
+	api   *RedditAPI
+	ctx   context.Context
+	u     *url.URL
+	query url.Values
+	opts  ListingOptions
+
+	children []Thing
+	thing    *Thing
+	yielded  int
+	cursor   Fullname
+	started  bool
+	done     bool
+	err      error
+}
+
+// newThingIterator creates an iterator over the listing at u. query
+// holds any extra parameters for the endpoint.
+func (api *RedditAPI) newThingIterator(ctx context.Context, u *url.URL, query url.Values, opts *ListingOptions) *ThingIterator {
+	it := &ThingIterator{
+		api:   api,
+		ctx:   ctx,
+		u:     u,
+		query: query,
+	}
+	if opts != nil {
+		it.opts = *opts
+	}
+	if it.opts.Before != "" {
+		it.cursor = it.opts.Before
+	} else {
+		it.cursor = it.opts.After
+	}
+	if it.opts.Limit < 0 || it.opts.Limit > maxListingLimit {
+		it.err = &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 0 and %d", maxListingLimit)}
+	}
+	return it
+}
+
+// Next advances to the next thing, fetching a new page if needed. It
+// returns false when the listing is exhausted or an error occurs.
+func (it *ThingIterator) Next() bool {
+	it.thing = nil
+	if it.err != nil {
+		return false
+	}
+	if it.opts.Max > 0 && it.yielded >= it.opts.Max {
+		return false
+	}
+
+	for len(it.children) == 0 {
+		// the first page is always fetched, later ones only if
+		// there is a cursor
+		if it.done || (it.started && it.cursor == "") {
+			return false
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			return false
+		}
+	}
+
+	it.thing = &it.children[0]
+	it.children = it.children[1:]
+	it.yielded++
+	return true
+}
+
+// Thing returns the current thing
+func (it *ThingIterator) Thing() *Thing {
+	return it.thing
+}
+
+// Err returns the error that stopped the iterator, if any
+func (it *ThingIterator) Err() error {
+	return it.err
+}
+
+// fetch gets the next page of the listing
+func (it *ThingIterator) fetch() error {
+	query := url.Values{
+		"raw_json": {"1"},
+	}
+	for key, vals := range it.query {
+		query[key] = vals
+	}
+
+	// request no more than is needed
+	limit := it.opts.Limit
+	if it.opts.Max > 0 {
+		remaining := it.opts.Max - it.yielded
+		if limit == 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	// set the cursor
+	if it.cursor != "" {
+		if it.opts.Before != "" {
+			query.Set("before", string(it.cursor))
+		} else {
+			query.Set("after", string(it.cursor))
+		}
+	}
+	if count := it.opts.Count + it.yielded; count > 0 {
+		query.Set("count", strconv.Itoa(count))
+	}
+
+	// copy the URL, as Get sets the query on it
+	u := *it.u
+	resp, err := it.api.GetContext(it.ctx, &u, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var thing Thing
+	if err := decodeResponse(resp, &thing); err != nil {
+		return err
+	}
+	page, ok := thing.Listing()
+	if !ok {
+		return errors.New(fmt.Sprintf("unexpected kind: %s", thing.Kind))
+	}
+
+	it.started = true
+	it.children = page.Children
+	if it.opts.Before != "" {
+		it.cursor = page.Before
+	} else {
+		it.cursor = page.After
+	}
+	if len(page.Children) == 0 {
+		it.done = true
+	}
+	return nil
+}
+
+// PostIterator iterates over the posts in a listing, fetching more
+// pages as needed. Call Next before each Post, and check Err once
+// Next returns false.
+type PostIterator struct {
+	it   *ThingIterator
+	post *PostResponse
+}
+
+// Next advances to the next post, returning false when there are no
+// more posts or an error occurred
+func (p *PostIterator) Next() bool {
+	for p.it.Next() {
+		if post, ok := p.it.Thing().Post(); ok {
+			p.post = post
+			return true
+		}
+	}
+	p.post = nil
+	return false
+}
+
+// Post returns the current post
+func (p *PostIterator) Post() *PostResponse {
+	return p.post
+}
+
+// Err returns the error that stopped the iterator, if any
+func (p *PostIterator) Err() error {
+	return p.it.Err()
+}
+
+// RequestSubredditListing returns an iterator over the posts in a
+// subreddit using the given sort. timeFilter is one of the Time
+// constants for the top and controversial sorts, and must be blank for
+// the others. opts may be nil.
+func (api *RedditAPI) RequestSubredditListing(subreddit, sort, timeFilter string, opts *ListingOptions) *PostIterator {
+	return api.RequestSubredditListingContext(context.Background(), subreddit, sort, timeFilter, opts)
+}
+
+// RequestSubredditListingContext is like RequestSubredditListing but
+// with a context
+func (api *RedditAPI) RequestSubredditListingContext(ctx context.Context, subreddit, sort, timeFilter string, opts *ListingOptions) *PostIterator {
+	u := api.GetOauthURL(OauthEndpointSubredditListing, subreddit, sort)
+
+	query := url.Values{}
+	var err error
+	switch sort {
+	case SortTop, SortControversial:
+		switch timeFilter {
+		case "":
+		case TimeHour, TimeDay, TimeWeek, TimeMonth, TimeYear, TimeAll:
+			query.Set("t", timeFilter)
+		default:
+			err = &ValidationError{Field: "t", Message: fmt.Sprintf("%q is not a time filter", timeFilter)}
+		}
+	case SortHot, SortNew, SortRising:
+		if timeFilter != "" {
+			err = &ValidationError{Field: "t", Message: fmt.Sprintf("not used for the %s sort", sort)}
+		}
+	default:
+		err = &ValidationError{Field: "sort", Message: fmt.Sprintf("%q is not a listing sort", sort)}
+	}
+
+	it := api.newThingIterator(ctx, u, query, opts)
+	if err != nil {
+		it.err = err
+	}
+	return &PostIterator{it: it}
+}
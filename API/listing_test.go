@@ -0,0 +1,149 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestSubredditListing(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	var want []string
+	for _, title := range []string{"one", "two", "three", "four", "five"} {
+		post := s.AddPost("test", "other", title, "")
+		want = append([]string{post.ID}, want...)
+	}
+
+	// pages of two are followed until the listing is exhausted
+	it := api.RequestSubredditListing("test", reddit.SortNew, "", &reddit.ListingOptions{Limit: 2})
+	var got []string
+	for it.Next() {
+		got = append(got, it.Post().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	// Max stops early
+	it = api.RequestSubredditListing("test", reddit.SortNew, "", &reddit.ListingOptions{Limit: 2, Max: 3})
+	n := 0
+	for it.Next() {
+		n++
+	}
+	if n != 3 || it.Err() != nil {
+		t.Errorf("got %d posts and %v with Max 3", n, it.Err())
+	}
+}
+
+// checkStatus checks that err is an *HTTPStatusError with the status
+func checkStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var statusErr *reddit.HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		t.Errorf("got %v, want an *HTTPStatusError", err)
+		return
+	}
+	if statusErr.StatusCode != status {
+		t.Errorf("got status %d, want %d", statusErr.StatusCode, status)
+	}
+}
+
+func TestListingStatusErrors(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	other := login(t, s, "other")
+
+	posts := api.RequestSubredditListing("nonexistent", reddit.SortHot, "", nil)
+	if posts.Next() {
+		t.Fatal("got a post from a nonexistent subreddit")
+	}
+	checkStatus(t, posts.Err(), http.StatusNotFound)
+
+	queue := other.RequestModListing("test", reddit.ModQueue, nil)
+	if queue.Next() {
+		t.Fatal("got the mod queue without moderating")
+	}
+	checkStatus(t, queue.Err(), http.StatusForbidden)
+
+	log := other.RequestModLog("test", nil, nil)
+	if log.Next() {
+		t.Fatal("got the mod log without moderating")
+	}
+	checkStatus(t, log.Err(), http.StatusForbidden)
+
+	banned := other.RequestRelationships("test", reddit.RelBanned, nil)
+	if banned.Next() {
+		t.Fatal("got the banned list without moderating")
+	}
+	checkStatus(t, banned.Err(), http.StatusForbidden)
+}
+
+func TestStreamStatusErrors(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 1)
+	opts := &reddit.StreamOptions{
+		MinInterval: time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+			cancel()
+		},
+	}
+	for range api.StreamSubmissions(ctx, opts, "nonexistent") {
+		t.Error("got a post from a nonexistent subreddit")
+	}
+	select {
+	case err := <-errs:
+		checkStatus(t, err, http.StatusNotFound)
+	default:
+		t.Fatal("stream stopped without an error")
+	}
+}
+
+func TestListingValidation(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	var validationErr *reddit.ValidationError
+	posts := api.RequestSubredditListing("test", "best", "", nil)
+	if posts.Next() || !errors.As(posts.Err(), &validationErr) || validationErr.Field != "sort" {
+		t.Errorf("got %v, want a *ValidationError for sort", posts.Err())
+	}
+	posts = api.RequestSubredditListing("test", reddit.SortTop, "decade", nil)
+	if posts.Next() || !errors.As(posts.Err(), &validationErr) || validationErr.Field != "t" {
+		t.Errorf("got %v, want a *ValidationError for t", posts.Err())
+	}
+	posts = api.RequestSubredditListing("test", reddit.SortHot, reddit.TimeWeek, nil)
+	if posts.Next() || !errors.As(posts.Err(), &validationErr) || validationErr.Field != "t" {
+		t.Errorf("got %v for a time filter on hot, want a *ValidationError for t", posts.Err())
+	}
+	posts = api.RequestSubredditListing("test", reddit.SortTop, reddit.TimeWeek, nil)
+	for posts.Next() {
+	}
+	if err := posts.Err(); err != nil {
+		t.Error(err)
+	}
+	posts = api.RequestSubredditListing("test", reddit.SortNew, "", &reddit.ListingOptions{Limit: 101})
+	if posts.Next() || !errors.As(posts.Err(), &validationErr) || validationErr.Field != "limit" {
+		t.Errorf("got %v, want a *ValidationError for limit", posts.Err())
+	}
+}
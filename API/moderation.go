@@ -0,0 +1,324 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// ways to distinguish a post or comment
+const (
+	DistinguishYes     = "yes" // as a moderator
+	DistinguishNo      = "no"
+	DistinguishAdmin   = "admin"
+	DistinguishSpecial = "special"
+)
+
+// suggested comment sorts for a post
+const (
+	SuggestedSortBest          = "confidence"
+	SuggestedSortTop           = "top"
+	SuggestedSortNew           = "new"
+	SuggestedSortControversial = "controversial"
+	SuggestedSortOld           = "old"
+	SuggestedSortRandom        = "random"
+	SuggestedSortQA            = "qa"
+	SuggestedSortLive          = "live"
+	SuggestedSortNone          = "blank" // clears the suggested sort
+)
+
+// moderation listings
+const (
+	ModQueue       = "modqueue"    // reported and filtered items
+	ModReports     = "reports"     // reported items
+	ModSpam        = "spam"        // removed items
+	ModEdited      = "edited"      // recently edited items
+	ModUnmoderated = "unmoderated" // posts not yet approved or removed
+)
+
+// maxModNoteLen is the longest mod note reddit will accept with a
+// removal
+const maxModNoteLen = 100
+
+// Approve approves a post or comment, restoring it if it was removed
+func (api *RedditAPI) Approve(name Fullname) error {
+	return api.ApproveContext(context.Background(), name)
+}
+
+// ApproveContext is like Approve but with a context
+func (api *RedditAPI) ApproveContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointApprove, name, KindPost, KindComment)
+}
+
+// IgnoreReports stops future reports on a post or comment from
+// notifying moderators
+func (api *RedditAPI) IgnoreReports(name Fullname) error {
+	return api.IgnoreReportsContext(context.Background(), name)
+}
+
+// IgnoreReportsContext is like IgnoreReports but with a context
+func (api *RedditAPI) IgnoreReportsContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointIgnoreReports, name, KindPost, KindComment)
+}
+
+// UnignoreReports undoes IgnoreReports
+func (api *RedditAPI) UnignoreReports(name Fullname) error {
+	return api.UnignoreReportsContext(context.Background(), name)
+}
+
+// UnignoreReportsContext is like UnignoreReports but with a context
+func (api *RedditAPI) UnignoreReportsContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointUnignoreReports, name, KindPost, KindComment)
+}
+
+// Lock prevents new comments on a post, or replies to a comment
+func (api *RedditAPI) Lock(name Fullname) error {
+	return api.LockContext(context.Background(), name)
+}
+
+// LockContext is like Lock but with a context
+func (api *RedditAPI) LockContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointLock, name, KindPost, KindComment)
+}
+
+// Unlock undoes Lock
+func (api *RedditAPI) Unlock(name Fullname) error {
+	return api.UnlockContext(context.Background(), name)
+}
+
+// UnlockContext is like Unlock but with a context
+func (api *RedditAPI) UnlockContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointUnlock, name, KindPost, KindComment)
+}
+
+// MarkNSFW marks a post as NSFW
+func (api *RedditAPI) MarkNSFW(name Fullname) error {
+	return api.MarkNSFWContext(context.Background(), name)
+}
+
+// MarkNSFWContext is like MarkNSFW but with a context
+func (api *RedditAPI) MarkNSFWContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointMarkNSFW, name, KindPost)
+}
+
+// UnmarkNSFW undoes MarkNSFW
+func (api *RedditAPI) UnmarkNSFW(name Fullname) error {
+	return api.UnmarkNSFWContext(context.Background(), name)
+}
+
+// UnmarkNSFWContext is like UnmarkNSFW but with a context
+func (api *RedditAPI) UnmarkNSFWContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointUnmarkNSFW, name, KindPost)
+}
+
+// MarkSpoiler marks a post as a spoiler
+func (api *RedditAPI) MarkSpoiler(name Fullname) error {
+	return api.MarkSpoilerContext(context.Background(), name)
+}
+
+// MarkSpoilerContext is like MarkSpoiler but with a context
+func (api *RedditAPI) MarkSpoilerContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointSpoiler, name, KindPost)
+}
+
+// UnmarkSpoiler undoes MarkSpoiler
+func (api *RedditAPI) UnmarkSpoiler(name Fullname) error {
+	return api.UnmarkSpoilerContext(context.Background(), name)
+}
+
+// UnmarkSpoilerContext is like UnmarkSpoiler but with a context
+func (api *RedditAPI) UnmarkSpoilerContext(ctx context.Context, name Fullname) error {
+	return api.modAction(ctx, OauthEndpointUnspoiler, name, KindPost)
+}
+
+// modAction sends a moderator action taking only the fullname of a
+// thing of one of the given kinds. reddit responds with a 403 if the
+// account does not moderate the subreddit.
+func (api *RedditAPI) modAction(ctx context.Context, endpoint string, name Fullname, kinds ...string) error {
+	if err := checkFullname(name, kinds...); err != nil {
+		return err
+	}
+
+	// construct post data
+	data := url.Values{
+		"id": {string(name)},
+	}
+
+	return api.postAction(ctx, endpoint, data, true)
+}
+
+// Distinguish distinguishes a post or comment, with how being one of
+// the Distinguish constants. sticky also stickies a top-level comment
+// to the top of its post, and may only be used with comments.
+// Returns the updated thing.
+func (api *RedditAPI) Distinguish(name Fullname, how string, sticky bool) (*Thing, error) {
+	return api.DistinguishContext(context.Background(), name, how, sticky)
+}
+
+// DistinguishContext is like Distinguish but with a context
+func (api *RedditAPI) DistinguishContext(ctx context.Context, name Fullname, how string, sticky bool) (*Thing, error) {
+	if err := checkFullname(name, KindPost, KindComment); err != nil {
+		return nil, err
+	}
+	switch how {
+	case DistinguishYes, DistinguishNo, DistinguishAdmin, DistinguishSpecial:
+	default:
+		return nil, &ValidationError{Field: "how", Message: fmt.Sprintf("%q is not a way to distinguish", how)}
+	}
+	if sticky && !name.IsComment() {
+		return nil, &ValidationError{Field: "sticky", Message: "only comments can be stickied by distinguishing"}
+	}
+	u := api.GetOauthURL(OauthEndpointDistinguish)
+
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"id":       {string(name)},
+		"how":      {how},
+		"raw_json": {"1"},
+	}
+	if name.IsComment() {
+		data.Set("sticky", strconv.FormatBool(sticky))
+	}
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response thingsResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	if err := response.Error(); err != nil {
+		return nil, err
+	}
+	if len(response.JSON.Data.Things) == 0 {
+		return nil, errors.New("no thing returned")
+	}
+
+	return &response.JSON.Data.Things[0], nil
+}
+
+// SetSuggestedSort sets the comment sort suggested for a post, with
+// sort being one of the SuggestedSort constants
+func (api *RedditAPI) SetSuggestedSort(name Fullname, sort string) error {
+	return api.SetSuggestedSortContext(context.Background(), name, sort)
+}
+
+// SetSuggestedSortContext is like SetSuggestedSort but with a context
+func (api *RedditAPI) SetSuggestedSortContext(ctx context.Context, name Fullname, sort string) error {
+	if err := checkFullname(name, KindPost); err != nil {
+		return err
+	}
+	switch sort {
+	case SuggestedSortBest, SuggestedSortTop, SuggestedSortNew, SuggestedSortControversial,
+		SuggestedSortOld, SuggestedSortRandom, SuggestedSortQA, SuggestedSortLive, SuggestedSortNone:
+	default:
+		return &ValidationError{Field: "sort", Message: fmt.Sprintf("%q is not a suggested sort", sort)}
+	}
+	u := api.GetOauthURL(OauthEndpointSetSuggestedSort)
+
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"id":       {string(name)},
+		"sort":     {sort},
+	}
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response BaseJSONResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return err
+	}
+	return response.Error()
+}
+
+// RemoveWithReason removes a post or comment like RequestRemovePost,
+// then records the removal reason and a note for other moderators.
+// reasonID is the ID of one of the subreddit's removal reasons. Either
+// may be blank.
+func (api *RedditAPI) RemoveWithReason(name Fullname, spam bool, reasonID, modNote string) error {
+	return api.RemoveWithReasonContext(context.Background(), name, spam, reasonID, modNote)
+}
+
+// RemoveWithReasonContext is like RemoveWithReason but with a context
+func (api *RedditAPI) RemoveWithReasonContext(ctx context.Context, name Fullname, spam bool, reasonID, modNote string) error {
+	if len([]rune(modNote)) > maxModNoteLen {
+		return &ValidationError{Field: "mod_note", Message: fmt.Sprintf("longer than %d characters", maxModNoteLen)}
+	}
+	if err := api.RequestRemovePostContext(ctx, name, spam); err != nil {
+		return err
+	}
+	if reasonID == "" && modNote == "" {
+		return nil
+	}
+	u := api.GetOauthURL(OauthEndpointRemovalReasons)
+
+	// construct post data, which is sent as JSON in a form field
+	body, err := json.Marshal(removalReasonRequest{
+		ItemIDs:  []Fullname{name},
+		ReasonID: reasonID,
+		ModNote:  modNote,
+	})
+	if err != nil {
+		return err
+	}
+	data := url.Values{
+		"json": {string(body)},
+	}
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response BaseResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return err
+	}
+	return response.Error()
+}
+
+// removalReasonRequest is the JSON form field for recording removal
+// reasons
+type removalReasonRequest struct {
+	ItemIDs  []Fullname `json:"item_ids"`
+	ReasonID string     `json:"reason_id,omitempty"`
+	ModNote  string     `json:"mod_note,omitempty"`
+}
+
+// RequestModListing returns an iterator over one of a subreddit's
+// moderation listings, with location being one of the Mod constants.
+// The listings mix posts and comments, with reports included for
+// moderators. opts may be nil.
+func (api *RedditAPI) RequestModListing(subreddit, location string, opts *ListingOptions) *ThingIterator {
+	return api.RequestModListingContext(context.Background(), subreddit, location, opts)
+}
+
+// RequestModListingContext is like RequestModListing but with a
+// context
+func (api *RedditAPI) RequestModListingContext(ctx context.Context, subreddit, location string, opts *ListingOptions) *ThingIterator {
+	u := api.GetOauthURL(OauthEndpointModListing, subreddit, location)
+	it := api.newThingIterator(ctx, u, url.Values{}, opts)
+
+	switch location {
+	case ModQueue, ModReports, ModSpam, ModEdited, ModUnmoderated:
+	default:
+		it.err = &ValidationError{Field: "location", Message: fmt.Sprintf("%q is not a moderation listing", location)}
+	}
+	return it
+}
@@ -0,0 +1,145 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestModActions(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "other", "title", "body")
+	name := reddit.Fullname(post.Fullname())
+
+	for _, action := range []func(reddit.Fullname) error{api.Lock, api.MarkNSFW, api.MarkSpoiler, api.IgnoreReports} {
+		if err := action(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stored, _ := s.Post(post.ID); !stored.Locked || !stored.NSFW || !stored.Spoiler || !stored.IgnoreReports {
+		t.Errorf("got %+v", stored)
+	}
+
+	if err := api.SetSuggestedSort(name, reddit.SuggestedSortQA); err != nil {
+		t.Fatal(err)
+	}
+	if err := api.RemoveWithReason(name, false, "r1", "note"); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := s.Post(post.ID)
+	if !stored.Removed || stored.RemovalReason != "r1" || stored.ModNote != "note" || stored.SuggestedSort != reddit.SuggestedSortQA {
+		t.Errorf("got %+v", stored)
+	}
+	if err := api.Approve(name); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := s.Post(post.ID); stored.Removed || !stored.Approved {
+		t.Errorf("got %+v after approving", stored)
+	}
+
+	// only moderators can moderate
+	other := login(t, s, "other")
+	checkStatus(t, other.Lock(name), http.StatusForbidden)
+}
+
+func TestDistinguish(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "other", "title", "body")
+	comment, _ := s.AddComment(post.Fullname(), "bot", "comment")
+
+	thing, err := api.Distinguish(reddit.Fullname(comment.Fullname()), reddit.DistinguishYes, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := thing.Comment(); !ok || got.Distinguished != "moderator" || !got.Stickied {
+		t.Errorf("got %+v", thing.Data)
+	}
+}
+
+func TestModerationValidation(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "other", "title", "body")
+	comment, _ := s.AddComment(post.Fullname(), "bot", "comment")
+	name := reddit.Fullname(post.Fullname())
+
+	if err := api.MarkNSFW(reddit.Fullname(comment.Fullname())); !errors.Is(err, reddit.ErrInvalidFullname) {
+		t.Errorf("got %v marking a comment NSFW, want ErrInvalidFullname", err)
+	}
+	var validationErr *reddit.ValidationError
+	if _, err := api.Distinguish(name, reddit.DistinguishYes, true); !errors.As(err, &validationErr) {
+		t.Errorf("got %v stickying a post by distinguishing, want a *ValidationError", err)
+	}
+	if err := api.SetSuggestedSort(name, "best"); !errors.As(err, &validationErr) {
+		t.Errorf("got %v for an unknown sort, want a *ValidationError", err)
+	}
+}
+
+// modListing returns the fullnames in one of test's moderation
+// listings
+func modListing(t *testing.T, api *reddit.RedditAPI, location string) []reddit.Fullname {
+	t.Helper()
+	it := api.RequestModListing("test", location, nil)
+	var names []reddit.Fullname
+	for it.Next() {
+		names = append(names, it.Thing().Fullname())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestModListings(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	reported := s.AddPost("test", "other", "reported", "body")
+	spam := s.AddPost("test", "other", "spam", "body")
+	comment, _ := s.AddComment(reported.Fullname(), "other", "comment")
+
+	other := login(t, s, "other")
+	for i := 0; i < 2; i++ {
+		if err := other.Report(reddit.Fullname(reported.Fullname()), "spam", "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other.Report(reddit.Fullname(comment.Fullname()), "", "rule 1", "")
+	api.Report(reddit.Fullname(comment.Fullname()), "", "", "mod report")
+
+	// reports are decoded for moderators
+	it := api.RequestModListing("test", reddit.ModReports, nil)
+	n := 0
+	for it.Next() {
+		n++
+		thing := it.Thing()
+		if post, ok := thing.Post(); ok {
+			if len(post.UserReports) != 1 || post.UserReports[0].Reason != "spam" || post.UserReports[0].Count != 2 {
+				t.Errorf("got user reports %+v", post.UserReports)
+			}
+		}
+		if c, ok := thing.Comment(); ok {
+			if len(c.UserReports) != 1 || len(c.ModReports) != 1 || c.ModReports[0].Moderator != "bot" || c.ModReports[0].Reason != "mod report" {
+				t.Errorf("got user reports %+v and mod reports %+v", c.UserReports, c.ModReports)
+			}
+		}
+	}
+	if it.Err() != nil || n != 2 {
+		t.Fatalf("got %d reported things and %v, want 2", n, it.Err())
+	}
+
+	api.Approve(reddit.Fullname(reported.Fullname()))
+	api.RequestRemovePost(reddit.Fullname(spam.Fullname()), true)
+	if got := modListing(t, api, reddit.ModQueue); len(got) != 1 || got[0] != reddit.Fullname(comment.Fullname()) {
+		t.Errorf("got mod queue %v", got)
+	}
+	if got := modListing(t, api, reddit.ModSpam); len(got) != 1 || got[0] != reddit.Fullname(spam.Fullname()) {
+		t.Errorf("got spam %v", got)
+	}
+	if got := modListing(t, api, reddit.ModUnmoderated); len(got) != 0 {
+		t.Errorf("got unmoderated %v", got)
+	}
+}
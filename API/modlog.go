@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// common moderation log actions
+const (
+	ModActionBanUser           = "banuser"
+	ModActionUnbanUser         = "unbanuser"
+	ModActionMuteUser          = "muteuser"
+	ModActionUnmuteUser        = "unmuteuser"
+	ModActionRemoveLink        = "removelink"
+	ModActionApproveLink       = "approvelink"
+	ModActionRemoveComment     = "removecomment"
+	ModActionApproveComment    = "approvecomment"
+	ModActionSpamLink          = "spamlink"
+	ModActionSpamComment       = "spamcomment"
+	ModActionLock              = "lock"
+	ModActionUnlock            = "unlock"
+	ModActionMarkNSFW          = "marknsfw"
+	ModActionSpoiler           = "spoiler"
+	ModActionUnspoiler         = "unspoiler"
+	ModActionSticky            = "sticky"
+	ModActionUnsticky          = "unsticky"
+	ModActionDistinguish       = "distinguish"
+	ModActionIgnoreReports     = "ignorereports"
+	ModActionUnignoreReports   = "unignorereports"
+	ModActionSetSuggestedSort  = "setsuggestedsort"
+	ModActionSetContestMode    = "setcontestmode"
+	ModActionUnsetContestMode  = "unsetcontestmode"
+	ModActionAddContributor    = "addcontributor"
+	ModActionRemoveContributor = "removecontributor"
+	ModActionInviteModerator   = "invitemoderator"
+	ModActionUninviteModerator = "uninvitemoderator"
+	ModActionAcceptModInvite   = "acceptmoderatorinvite"
+	ModActionRemoveModerator   = "removemoderator"
+	ModActionWikiRevise        = "wikirevise"
+	ModActionEditSettings      = "editsettings"
+)
+
+// ModLogFilter narrows the entries in a moderation log
+type ModLogFilter struct {
+	// Action is one of the ModAction constants, or blank for all
+	// actions
+	Action string
+	// Moderators lists the moderators whose actions are wanted, or is
+	// empty for all of them
+	Moderators []string
+}
+
+// query returns the query parameters for the filter, which may be nil
+func (f *ModLogFilter) query() url.Values {
+	query := url.Values{}
+	if f == nil {
+		return query
+	}
+	if f.Action != "" {
+		query.Set("type", f.Action)
+	}
+	if len(f.Moderators) > 0 {
+		query.Set("mod", strings.Join(f.Moderators, ","))
+	}
+	return query
+}
+
+// ModActionIterator iterates over the entries in a moderation log,
+// newest first, fetching more pages as needed. Call Next before each
+// ModAction, and check Err once Next returns false.
+type ModActionIterator struct {
+	it     *ThingIterator
+	action *ModAction
+}
+
+// Next advances to the next entry, returning false when there are no
+// more entries or an error occurred
+func (m *ModActionIterator) Next() bool {
+	for m.it.Next() {
+		if action, ok := m.it.Thing().ModAction(); ok {
+			m.action = action
+			return true
+		}
+	}
+	m.action = nil
+	return false
+}
+
+// ModAction returns the current entry
+func (m *ModActionIterator) ModAction() *ModAction {
+	return m.action
+}
+
+// Err returns the error that stopped the iterator, if any
+func (m *ModActionIterator) Err() error {
+	return m.it.Err()
+}
+
+// RequestModLog returns an iterator over a subreddit's moderation log.
+// filter and opts may be nil.
+func (api *RedditAPI) RequestModLog(subreddit string, filter *ModLogFilter, opts *ListingOptions) *ModActionIterator {
+	return api.RequestModLogContext(context.Background(), subreddit, filter, opts)
+}
+
+// RequestModLogContext is like RequestModLog but with a context
+func (api *RedditAPI) RequestModLogContext(ctx context.Context, subreddit string, filter *ModLogFilter, opts *ListingOptions) *ModActionIterator {
+	u := api.GetOauthURL(OauthEndpointModLog, subreddit)
+	return &ModActionIterator{
+		it: api.newThingIterator(ctx, u, filter.query(), opts),
+	}
+}
+
+// StreamModLog delivers new entries in a subreddit's moderation log,
+// oldest first, until the context is cancelled. The channel is closed
+// when the stream stops, which is straight away if subreddit is
+// blank. filter and opts may be nil.
+func (api *RedditAPI) StreamModLog(ctx context.Context, subreddit string, filter *ModLogFilter, opts *StreamOptions) <-chan *ModAction {
+	err := validateSubreddits([]string{subreddit})
+	u := api.GetOauthURL(OauthEndpointModLog, subreddit)
+	actions := make(chan *ModAction)
+
+	go func() {
+		defer close(actions)
+		if err != nil {
+			opts.withDefaults().OnError(err)
+			return
+		}
+		api.stream(ctx, opts, u, filter.query(), func(thing *Thing) (Fullname, interface{}, bool) {
+			action, ok := thing.ModAction()
+			return thing.Fullname(), action, ok
+		}, func(item interface{}) bool {
+			select {
+			case actions <- item.(*ModAction):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return actions
+}
@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+	"github.com/joshbarrass/goreddit/fakereddit"
+)
+
+// modLogActions returns the actions in test's mod log
+func modLogActions(t *testing.T, api *reddit.RedditAPI, filter *reddit.ModLogFilter, opts *reddit.ListingOptions) []string {
+	t.Helper()
+	it := api.RequestModLog("test", filter, opts)
+	var actions []string
+	for it.Next() {
+		actions = append(actions, it.ModAction().Action)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return actions
+}
+
+func TestModLog(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	s.AddUser("mod2", "pw")
+	post := s.AddPost("test", "other", "title", "body")
+	name := reddit.Fullname(post.Fullname())
+	api.Lock(name)
+	api.Unlock(name)
+	api.RequestRemovePost(name, false)
+
+	// newest first, across pages
+	it := api.RequestModLog("test", nil, &reddit.ListingOptions{Limit: 2})
+	var actions []string
+	for it.Next() {
+		action := it.ModAction()
+		actions = append(actions, action.Action)
+		if action.Moderator != "bot" || action.TargetFullname != name || action.TargetAuthor != "other" || time.Time(action.CreatedUTC).IsZero() {
+			t.Errorf("got %+v", action)
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(actions, ","); got != "removelink,unlock,lock" {
+		t.Errorf("got actions %s", got)
+	}
+
+	if got := modLogActions(t, api, &reddit.ModLogFilter{Action: reddit.ModActionLock}, nil); len(got) != 1 {
+		t.Errorf("got %v filtering by action", got)
+	}
+	if got := modLogActions(t, api, &reddit.ModLogFilter{Moderators: []string{"mod2"}}, nil); len(got) != 0 {
+		t.Errorf("got %v filtering by moderator", got)
+	}
+}
+
+func TestStreamModLog(t *testing.T) {
+	s := fakereddit.NewServer("id", "secret")
+	defer s.Close()
+	// keep the rate limit from slowing down polling
+	s.RateLimitWindow = time.Second
+	s.AddUser("bot", "pw")
+	s.AddSubreddit("test", "bot")
+	api := login(t, s, "bot")
+	post := s.AddPost("test", "bot", "title", "body")
+	name := reddit.Fullname(post.Fullname())
+	api.Lock(name)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := &reddit.StreamOptions{
+		MinInterval: time.Millisecond,
+		MaxInterval: time.Millisecond,
+		OnError:     func(err error) { t.Error(err) },
+	}
+	filter := &reddit.ModLogFilter{Action: reddit.ModActionApproveLink}
+	actions := api.StreamModLog(ctx, "test", filter, opts)
+
+	// only the filtered action is delivered
+	api.Unlock(name)
+	api.Approve(name)
+	select {
+	case action := <-actions:
+		if action.Action != reddit.ModActionApproveLink || action.TargetFullname != name {
+			t.Errorf("got %+v", action)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the approval")
+	}
+}
@@ -0,0 +1,579 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// modmail folders to list conversations from
+const (
+	ModmailNew           = "new"
+	ModmailInProgress    = "inprogress"
+	ModmailArchived      = "archived"
+	ModmailMod           = "mod" // discussions between moderators
+	ModmailNotifications = "notifications"
+	ModmailHighlighted   = "highlighted"
+	ModmailAll           = "all"
+)
+
+// modmail conversation sorts
+const (
+	ModmailSortRecent = "recent"
+	ModmailSortMod    = "mod"    // latest moderator reply first
+	ModmailSortUser   = "user"   // latest user reply first
+	ModmailSortUnread = "unread" // unread conversations first
+)
+
+// modmail conversation states
+const (
+	ModmailStateNew        = 0
+	ModmailStateInProgress = 1
+	ModmailStateArchived   = 2
+)
+
+// modmail moderator action types
+const (
+	ModmailActionHighlight        = 0
+	ModmailActionUnhighlight      = 1
+	ModmailActionArchive          = 2
+	ModmailActionUnarchive        = 3
+	ModmailActionReportedToAdmins = 4
+	ModmailActionMute             = 5
+	ModmailActionUnmute           = 6
+)
+
+// lengths of time a user can be muted from modmail, in hours
+const (
+	ModmailMute3Days  = 72
+	ModmailMute7Days  = 168
+	ModmailMute28Days = 672
+)
+
+// maxModmailLen is the longest modmail message reddit will accept
+const maxModmailLen = 10000
+
+// ModmailConversation is a conversation in new modmail. Messages and
+// ModActions hold those included in the response, oldest first.
+type ModmailConversation struct {
+	ID            string          `json:"id"`
+	Subject       string          `json:"subject"`
+	State         int             `json:"state"`
+	IsHighlighted bool            `json:"isHighlighted"`
+	IsInternal    bool            `json:"isInternal"`
+	IsAuto        bool            `json:"isAuto"`
+	IsRepliable   bool            `json:"isRepliable"`
+	NumMessages   int             `json:"numMessages"`
+	Owner         ModmailOwner    `json:"owner"`
+	Participant   ModmailAuthor   `json:"participant"`
+	Authors       []ModmailAuthor `json:"authors"`
+	ObjIDs        []ModmailObjID  `json:"objIds"`
+
+	LastUpdated    time.Time `json:"lastUpdated"`
+	LastUserUpdate time.Time `json:"lastUserUpdate"`
+	LastModUpdate  time.Time `json:"lastModUpdate"`
+	// LastUnread is zero if the conversation has been read
+	LastUnread time.Time `json:"lastUnread"`
+
+	Messages   []*ModmailMessage   `json:"-"`
+	ModActions []*ModmailModAction `json:"-"`
+}
+
+// Unread reports whether the conversation has unread messages
+func (c *ModmailConversation) Unread() bool {
+	return !c.LastUnread.IsZero()
+}
+
+// resolve fills in the messages and actions of the conversation from
+// those in a response
+func (c *ModmailConversation) resolve(messages map[string]*ModmailMessage, actions map[string]*ModmailModAction) {
+	c.Messages = nil
+	c.ModActions = nil
+	for _, obj := range c.ObjIDs {
+		switch obj.Key {
+		case "messages":
+			if message, ok := messages[obj.ID]; ok {
+				c.Messages = append(c.Messages, message)
+			}
+		case "modActions":
+			if action, ok := actions[obj.ID]; ok {
+				c.ModActions = append(c.ModActions, action)
+			}
+		}
+	}
+}
+
+// ModmailOwner is the subreddit a conversation belongs to
+type ModmailOwner struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	Type        string `json:"type"`
+}
+
+// ModmailAuthor is a user taking part in a conversation. ID is the
+// numeric form of the account's ID.
+type ModmailAuthor struct {
+	ID            int64  `json:"id"`
+	Name          string `json:"name"`
+	IsMod         bool   `json:"isMod"`
+	IsAdmin       bool   `json:"isAdmin"`
+	IsOP          bool   `json:"isOp"`
+	IsParticipant bool   `json:"isParticipant"`
+	IsHidden      bool   `json:"isHidden"`
+	IsDeleted     bool   `json:"isDeleted"`
+}
+
+// ModmailObjID refers to a message or moderator action in a
+// conversation, with Key being "messages" or "modActions"
+type ModmailObjID struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// ModmailMessage is a message in a conversation. Body is HTML.
+// Internal messages are only shown to moderators.
+type ModmailMessage struct {
+	ID           string        `json:"id"`
+	Body         string        `json:"body"`
+	BodyMarkdown string        `json:"bodyMarkdown"`
+	Author       ModmailAuthor `json:"author"`
+	Date         time.Time     `json:"date"`
+	IsInternal   bool          `json:"isInternal"`
+}
+
+// ModmailModAction is a moderator action on a conversation, with
+// ActionTypeID being one of the ModmailAction constants
+type ModmailModAction struct {
+	ID           string        `json:"id"`
+	ActionTypeID int           `json:"actionTypeId"`
+	Author       ModmailAuthor `json:"author"`
+	Date         time.Time     `json:"date"`
+}
+
+// modmailConversationsResponse is a page of conversations
+type modmailConversationsResponse struct {
+	BaseResponse
+	Conversations   map[string]*ModmailConversation `json:"conversations"`
+	ConversationIDs []string                        `json:"conversationIds"`
+	Messages        map[string]*ModmailMessage      `json:"messages"`
+}
+
+// modmailConversationResponse is a single conversation
+type modmailConversationResponse struct {
+	BaseResponse
+	Conversation *ModmailConversation         `json:"conversation"`
+	Messages     map[string]*ModmailMessage   `json:"messages"`
+	ModActions   map[string]*ModmailModAction `json:"modActions"`
+}
+
+// conversation returns the conversation with its messages and actions
+func (r *modmailConversationResponse) conversation() (*ModmailConversation, error) {
+	if err := r.Error(); err != nil {
+		return nil, err
+	}
+	if r.Conversation == nil {
+		return nil, errors.New("no conversation returned")
+	}
+	r.Conversation.resolve(r.Messages, r.ModActions)
+	return r.Conversation, nil
+}
+
+// ModmailFilter narrows the conversations listed
+type ModmailFilter struct {
+	// Subreddits lists the subreddits to list conversations from, or
+	// is empty for every subreddit the account moderates
+	Subreddits []string
+	// State is one of the Modmail folder constants, or blank for
+	// ModmailAll
+	State string
+	// Sort is one of the ModmailSort constants, or blank for
+	// ModmailSortRecent
+	Sort string
+}
+
+// query returns the query parameters for the filter, which may be nil
+func (f *ModmailFilter) query() (url.Values, error) {
+	query := url.Values{}
+	if f == nil {
+		return query, nil
+	}
+	if len(f.Subreddits) > 0 {
+		query.Set("entity", strings.Join(f.Subreddits, ","))
+	}
+	switch f.State {
+	case "":
+	case ModmailNew, ModmailInProgress, ModmailArchived, ModmailMod, ModmailNotifications, ModmailHighlighted, ModmailAll:
+		query.Set("state", f.State)
+	default:
+		return nil, &ValidationError{Field: "state", Message: fmt.Sprintf("%q is not a modmail state", f.State)}
+	}
+	switch f.Sort {
+	case "":
+	case ModmailSortRecent, ModmailSortMod, ModmailSortUser, ModmailSortUnread:
+		query.Set("sort", f.Sort)
+	default:
+		return nil, &ValidationError{Field: "sort", Message: fmt.Sprintf("%q is not a modmail sort", f.Sort)}
+	}
+	return query, nil
+}
+
+// ModmailIterator iterates over modmail conversations, fetching more
+// pages as needed. Call Next before each ModmailConversation, and
+// check Err once Next returns false.
+type ModmailIterator struct {
+	api   *RedditAPI
+	ctx   context.Context
+	query url.Values
+	opts  ListingOptions
+
+	page         []*ModmailConversation
+	conversation *ModmailConversation
+	yielded      int
+	cursor       string
+	done         bool
+	err          error
+}
+
+// Next advances to the next conversation, fetching a new page if
+// needed. It returns false when there are no more conversations or an
+// error occurred.
+func (m *ModmailIterator) Next() bool {
+	m.conversation = nil
+	if m.err != nil {
+		return false
+	}
+	if m.opts.Max > 0 && m.yielded >= m.opts.Max {
+		return false
+	}
+
+	for len(m.page) == 0 {
+		if m.done {
+			return false
+		}
+		if err := m.fetch(); err != nil {
+			m.err = err
+			return false
+		}
+	}
+
+	m.conversation = m.page[0]
+	m.page = m.page[1:]
+	m.yielded++
+	return true
+}
+
+// Conversation returns the current conversation
+func (m *ModmailIterator) Conversation() *ModmailConversation {
+	return m.conversation
+}
+
+// Err returns the error that stopped the iterator, if any
+func (m *ModmailIterator) Err() error {
+	return m.err
+}
+
+// fetch gets the next page of conversations
+func (m *ModmailIterator) fetch() error {
+	query := url.Values{}
+	for key, vals := range m.query {
+		query[key] = vals
+	}
+
+	// request no more than is needed
+	limit := m.opts.Limit
+	if m.opts.Max > 0 {
+		remaining := m.opts.Max - m.yielded
+		if limit == 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if m.cursor != "" {
+		query.Set("after", m.cursor)
+	}
+
+	u := m.api.GetOauthURL(OauthEndpointModmail)
+	resp, err := m.api.GetContext(m.ctx, u, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response modmailConversationsResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return err
+	}
+	if err := response.Error(); err != nil {
+		return err
+	}
+
+	m.page = nil
+	for _, id := range response.ConversationIDs {
+		if conversation, ok := response.Conversations[id]; ok {
+			conversation.resolve(response.Messages, nil)
+			m.page = append(m.page, conversation)
+		}
+	}
+	if len(response.ConversationIDs) == 0 {
+		m.done = true
+	} else {
+		m.cursor = response.ConversationIDs[len(response.ConversationIDs)-1]
+	}
+	return nil
+}
+
+// RequestModmail returns an iterator over the account's modmail
+// conversations, most recent first. Each conversation includes only
+// its latest messages; use RequestModmailConversation for the rest.
+// opts.After may be a conversation ID, and Before and Count are not
+// supported. filter and opts may be nil.
+func (api *RedditAPI) RequestModmail(filter *ModmailFilter, opts *ListingOptions) *ModmailIterator {
+	return api.RequestModmailContext(context.Background(), filter, opts)
+}
+
+// RequestModmailContext is like RequestModmail but with a context
+func (api *RedditAPI) RequestModmailContext(ctx context.Context, filter *ModmailFilter, opts *ListingOptions) *ModmailIterator {
+	it := &ModmailIterator{
+		api: api,
+		ctx: ctx,
+	}
+	if opts != nil {
+		it.opts = *opts
+	}
+	it.cursor = string(it.opts.After)
+	if it.opts.Limit < 0 || it.opts.Limit > maxListingLimit {
+		it.err = &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 0 and %d", maxListingLimit)}
+	}
+	query, err := filter.query()
+	if err != nil {
+		it.err = err
+	}
+	it.query = query
+	return it
+}
+
+// RequestModmailConversation gets a modmail conversation with all of
+// its messages and moderator actions, marking it as read if markRead
+// is set
+func (api *RedditAPI) RequestModmailConversation(id string, markRead bool) (*ModmailConversation, error) {
+	return api.RequestModmailConversationContext(context.Background(), id, markRead)
+}
+
+// RequestModmailConversationContext is like
+// RequestModmailConversation but with a context
+func (api *RedditAPI) RequestModmailConversationContext(ctx context.Context, id string, markRead bool) (*ModmailConversation, error) {
+	if id == "" {
+		return nil, &ValidationError{Field: "id", Message: "no conversation ID"}
+	}
+	u := api.GetOauthURL(OauthEndpointModmailThread, id)
+
+	// construct query
+	query := url.Values{
+		"markRead": {strconv.FormatBool(markRead)},
+	}
+
+	// send request
+	resp, err := api.GetContext(ctx, u, query)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response modmailConversationResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	return response.conversation()
+}
+
+// ReplyModmail replies to a modmail conversation with a markdown
+// message. Internal messages are only shown to moderators. Returns
+// the updated conversation.
+func (api *RedditAPI) ReplyModmail(id, markdown string, internal bool) (*ModmailConversation, error) {
+	return api.ReplyModmailContext(context.Background(), id, markdown, internal)
+}
+
+// ReplyModmailContext is like ReplyModmail but with a context
+func (api *RedditAPI) ReplyModmailContext(ctx context.Context, id, markdown string, internal bool) (*ModmailConversation, error) {
+	if id == "" {
+		return nil, &ValidationError{Field: "id", Message: "no conversation ID"}
+	}
+	if strings.TrimSpace(markdown) == "" {
+		return nil, &ValidationError{Field: "body", Message: "no text"}
+	}
+	if len([]rune(markdown)) > maxModmailLen {
+		return nil, &ValidationError{Field: "body", Message: fmt.Sprintf("longer than %d characters", maxModmailLen)}
+	}
+
+	// construct post data
+	data := url.Values{
+		"body":           {markdown},
+		"isInternal":     {strconv.FormatBool(internal)},
+		"isAuthorHidden": {"false"},
+	}
+
+	return api.modmailRequest(ctx, http.MethodPost, api.GetOauthURL(OauthEndpointModmailThread, id), data, false)
+}
+
+// ArchiveModmail archives a modmail conversation, returning the
+// updated conversation
+func (api *RedditAPI) ArchiveModmail(id string) (*ModmailConversation, error) {
+	return api.ArchiveModmailContext(context.Background(), id)
+}
+
+// ArchiveModmailContext is like ArchiveModmail but with a context
+func (api *RedditAPI) ArchiveModmailContext(ctx context.Context, id string) (*ModmailConversation, error) {
+	return api.modmailAction(ctx, http.MethodPost, id, "archive", url.Values{}, true)
+}
+
+// UnarchiveModmail undoes ArchiveModmail
+func (api *RedditAPI) UnarchiveModmail(id string) (*ModmailConversation, error) {
+	return api.UnarchiveModmailContext(context.Background(), id)
+}
+
+// UnarchiveModmailContext is like UnarchiveModmail but with a context
+func (api *RedditAPI) UnarchiveModmailContext(ctx context.Context, id string) (*ModmailConversation, error) {
+	return api.modmailAction(ctx, http.MethodPost, id, "unarchive", url.Values{}, true)
+}
+
+// HighlightModmail highlights a modmail conversation, returning the
+// updated conversation
+func (api *RedditAPI) HighlightModmail(id string) (*ModmailConversation, error) {
+	return api.HighlightModmailContext(context.Background(), id)
+}
+
+// HighlightModmailContext is like HighlightModmail but with a context
+func (api *RedditAPI) HighlightModmailContext(ctx context.Context, id string) (*ModmailConversation, error) {
+	return api.modmailAction(ctx, http.MethodPost, id, "highlight", url.Values{}, true)
+}
+
+// UnhighlightModmail undoes HighlightModmail
+func (api *RedditAPI) UnhighlightModmail(id string) (*ModmailConversation, error) {
+	return api.UnhighlightModmailContext(context.Background(), id)
+}
+
+// UnhighlightModmailContext is like UnhighlightModmail but with a
+// context
+func (api *RedditAPI) UnhighlightModmailContext(ctx context.Context, id string) (*ModmailConversation, error) {
+	return api.modmailAction(ctx, http.MethodDelete, id, "highlight", url.Values{}, true)
+}
+
+// MuteModmailUser mutes the user in a modmail conversation for one of
+// the ModmailMute durations, returning the updated conversation
+func (api *RedditAPI) MuteModmailUser(id string, hours int) (*ModmailConversation, error) {
+	return api.MuteModmailUserContext(context.Background(), id, hours)
+}
+
+// MuteModmailUserContext is like MuteModmailUser but with a context
+func (api *RedditAPI) MuteModmailUserContext(ctx context.Context, id string, hours int) (*ModmailConversation, error) {
+	switch hours {
+	case ModmailMute3Days, ModmailMute7Days, ModmailMute28Days:
+	default:
+		return nil, &ValidationError{Field: "num_hours", Message: fmt.Sprintf("%d is not a mute duration", hours)}
+	}
+
+	// construct post data
+	data := url.Values{
+		"num_hours": {strconv.Itoa(hours)},
+	}
+
+	return api.modmailAction(ctx, http.MethodPost, id, "mute", data, false)
+}
+
+// UnmuteModmailUser undoes MuteModmailUser
+func (api *RedditAPI) UnmuteModmailUser(id string) (*ModmailConversation, error) {
+	return api.UnmuteModmailUserContext(context.Background(), id)
+}
+
+// UnmuteModmailUserContext is like UnmuteModmailUser but with a
+// context
+func (api *RedditAPI) UnmuteModmailUserContext(ctx context.Context, id string) (*ModmailConversation, error) {
+	return api.modmailAction(ctx, http.MethodPost, id, "unmute", url.Values{}, false)
+}
+
+// modmailAction sends an action on a conversation. Actions that
+// notify the user, such as muting, are not idempotent.
+func (api *RedditAPI) modmailAction(ctx context.Context, method, id, action string, data url.Values, idempotent bool) (*ModmailConversation, error) {
+	if id == "" {
+		return nil, &ValidationError{Field: "id", Message: "no conversation ID"}
+	}
+	u := api.GetOauthURL(OauthEndpointModmailAction, id, action)
+	return api.modmailRequest(ctx, method, u, data, idempotent)
+}
+
+// modmailRequest sends a request to an endpoint that returns the
+// updated conversation. DELETE requests carry no data.
+func (api *RedditAPI) modmailRequest(ctx context.Context, method string, u *url.URL, data url.Values, idempotent bool) (*ModmailConversation, error) {
+	// send request
+	var (
+		resp *http.Response
+		err  error
+	)
+	if method == http.MethodDelete {
+		var req *http.Request
+		req, err = api.NewRequestContext(ctx, method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = api.do(req)
+	} else {
+		resp, err = api.postForm(ctx, u, data, idempotent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response modmailConversationResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	return response.conversation()
+}
+
+// MarkModmailRead marks modmail conversations as read
+func (api *RedditAPI) MarkModmailRead(ids ...string) error {
+	return api.MarkModmailReadContext(context.Background(), ids...)
+}
+
+// MarkModmailReadContext is like MarkModmailRead but with a context
+func (api *RedditAPI) MarkModmailReadContext(ctx context.Context, ids ...string) error {
+	return api.markModmail(ctx, OauthEndpointModmailRead, ids)
+}
+
+// MarkModmailUnread marks modmail conversations as unread
+func (api *RedditAPI) MarkModmailUnread(ids ...string) error {
+	return api.MarkModmailUnreadContext(context.Background(), ids...)
+}
+
+// MarkModmailUnreadContext is like MarkModmailUnread but with a
+// context
+func (api *RedditAPI) MarkModmailUnreadContext(ctx context.Context, ids ...string) error {
+	return api.markModmail(ctx, OauthEndpointModmailUnread, ids)
+}
+
+// markModmail marks conversations as read or unread
+func (api *RedditAPI) markModmail(ctx context.Context, endpoint string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	for _, id := range ids {
+		if id == "" {
+			return &ValidationError{Field: "conversationIds", Message: "empty conversation ID"}
+		}
+	}
+
+	// construct post data
+	data := url.Values{
+		"conversationIds": {strings.Join(ids, ",")},
+	}
+
+	return api.postAction(ctx, endpoint, data, true)
+}
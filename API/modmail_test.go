@@ -0,0 +1,69 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestModmail(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	first, _ := s.AddConversation("test", "other", "first", "help")
+	second, _ := s.AddConversation("test", "other", "second", "please")
+
+	// conversations come newest first
+	it := api.RequestModmail(&reddit.ModmailFilter{State: reddit.ModmailNew}, &reddit.ListingOptions{Limit: 1})
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Conversation().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != second.ID || ids[1] != first.ID {
+		t.Fatalf("got %v, want [%s %s]", ids, second.ID, first.ID)
+	}
+
+	conv, err := api.ReplyModmail(first.ID, "hello", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.State != reddit.ModmailStateInProgress || len(conv.Messages) != 2 || conv.Messages[1].BodyMarkdown != "hello" {
+		t.Errorf("got %+v after replying", conv)
+	}
+
+	if _, err := api.MuteModmailUser(first.ID, reddit.ModmailMute3Days); err != nil {
+		t.Fatal(err)
+	}
+	if sub, _ := s.Subreddit("test"); len(sub.Muted) != 1 || sub.Muted[0].Name != "other" {
+		t.Errorf("got muted users %+v", sub.Muted)
+	}
+	conv, err = api.ArchiveModmail(first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.State != reddit.ModmailStateArchived {
+		t.Errorf("got state %d after archiving", conv.State)
+	}
+}
+
+func TestModmailFilterValidation(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	var validationErr *reddit.ValidationError
+	it := api.RequestModmail(&reddit.ModmailFilter{State: "unread"}, nil)
+	if it.Next() || !errors.As(it.Err(), &validationErr) || validationErr.Field != "state" {
+		t.Errorf("got %v, want a *ValidationError for state", it.Err())
+	}
+	it = api.RequestModmail(&reddit.ModmailFilter{Sort: "oldest"}, nil)
+	if it.Next() || !errors.As(it.Err(), &validationErr) || validationErr.Field != "sort" {
+		t.Errorf("got %v, want a *ValidationError for sort", it.Err())
+	}
+	it = api.RequestModmail(nil, &reddit.ListingOptions{Limit: 500})
+	if it.Next() || !errors.As(it.Err(), &validationErr) || validationErr.Field != "limit" {
+		t.Errorf("got %v, want a *ValidationError for limit", it.Err())
+	}
+}
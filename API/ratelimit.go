@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitMode determines what happens when a request would exceed
+// reddit's rate limit
+type RateLimitMode int
+
+// rate limit modes
+const (
+	// RateLimitBlock waits until the rate limit resets before
+	// sending the request
+	RateLimitBlock RateLimitMode = iota
+	// RateLimitReturnError returns a *RateLimitError instead of
+	// sending the request
+	RateLimitReturnError
+	// RateLimitIgnore sends requests regardless of the rate limit
+	RateLimitIgnore
+)
+
+// rate limit headers
+const (
+	headerRateLimitUsed      = "X-Ratelimit-Used"
+	headerRateLimitRemaining = "X-Ratelimit-Remaining"
+	headerRateLimitReset     = "X-Ratelimit-Reset"
+)
+
+// RateLimit is the request budget reported by reddit in the
+// X-Ratelimit headers
+type RateLimit struct {
+	Used      int
+	Remaining float64
+	Reset     time.Time
+}
+
+// RateLimitError is returned when a request would exceed the rate
+// limit and the API is set to RateLimitReturnError, or when reddit
+// still refuses a request with a 429 after retrying
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, resets in %s", time.Until(e.Reset).Round(time.Second))
+}
+
+// rateLimiter tracks the rate limit across all requests made by an
+// API
+type rateLimiter struct {
+	mu    sync.Mutex
+	limit RateLimit
+	known bool
+}
+
+// reserve takes a request from the budget. If there is nothing left,
+// it returns false and the time at which the budget resets.
+func (l *rateLimiter) reserve() (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// no budget known, or the window has reset
+	if !l.known || time.Now().After(l.limit.Reset) {
+		l.known = false
+		return time.Time{}, true
+	}
+
+	if l.limit.Remaining < 1 {
+		return l.limit.Reset, false
+	}
+
+	// count the request now so that concurrent requests don't
+	// overshoot before the response arrives
+	l.limit.Remaining--
+	l.limit.Used++
+	return time.Time{}, true
+}
+
+// update sets the budget from the headers of a response
+func (l *rateLimiter) update(header http.Header) {
+	remaining, err := strconv.ParseFloat(header.Get(headerRateLimitRemaining), 64)
+	if err != nil {
+		return
+	}
+	reset, err := strconv.Atoi(header.Get(headerRateLimitReset))
+	if err != nil {
+		return
+	}
+	used, _ := strconv.Atoi(header.Get(headerRateLimitUsed))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = RateLimit{
+		Used:      used,
+		Remaining: remaining,
+		Reset:     time.Now().Add(time.Duration(reset) * time.Second),
+	}
+	l.known = true
+}
+
+// rateLimitReset returns when a 429 response says to try again, from
+// the Retry-After header or failing that the rate limit reset
+func rateLimitReset(header http.Header) time.Time {
+	for _, key := range []string{"Retry-After", headerRateLimitReset} {
+		if seconds, err := strconv.Atoi(header.Get(key)); err == nil {
+			return time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+	}
+	return time.Now()
+}
+
+// get returns the current budget
+func (l *rateLimiter) get() RateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// RateLimit returns the most recent rate limit budget reported by
+// reddit, less any requests sent since
+func (api *RedditAPI) RateLimit() RateLimit {
+	return api.rateLimiter.get()
+}
+
+// waitRateLimit takes a request from the rate limit budget, waiting
+// for the budget to reset or returning an error if there is none left
+func (api *RedditAPI) waitRateLimit(ctx context.Context) error {
+	if api.RateLimitMode == RateLimitIgnore {
+		return nil
+	}
+
+	for {
+		reset, ok := api.rateLimiter.reserve()
+		if ok {
+			return nil
+		}
+		if api.RateLimitMode == RateLimitReturnError {
+			return &RateLimitError{Reset: reset}
+		}
+		if err := sleepContext(ctx, time.Until(reset)); err != nil {
+			return err
+		}
+	}
+}
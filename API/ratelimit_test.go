@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	var l rateLimiter
+
+	// nothing is known before the first response
+	if _, ok := l.reserve(); !ok {
+		t.Fatal("request refused with no known budget")
+	}
+
+	header := http.Header{}
+	header.Set(headerRateLimitUsed, "598")
+	header.Set(headerRateLimitRemaining, "2.0")
+	header.Set(headerRateLimitReset, "60")
+	l.update(header)
+	for i := 0; i < 2; i++ {
+		if _, ok := l.reserve(); !ok {
+			t.Fatalf("request %d refused", i+1)
+		}
+	}
+	reset, ok := l.reserve()
+	if ok {
+		t.Fatal("request allowed beyond the budget")
+	}
+	if wait := time.Until(reset); wait < 59*time.Second || wait > 60*time.Second {
+		t.Errorf("got reset in %s, want 60s", wait)
+	}
+	if got := l.get(); got.Used != 600 || got.Remaining != 0 {
+		t.Errorf("got %+v", got)
+	}
+
+	// the budget is forgotten once the window resets
+	header.Set(headerRateLimitRemaining, "0.0")
+	header.Set(headerRateLimitReset, "0")
+	l.update(header)
+	time.Sleep(time.Millisecond)
+	if _, ok := l.reserve(); !ok {
+		t.Error("request refused after the window reset")
+	}
+}
+
+func TestRateLimitModes(t *testing.T) {
+	var requests int32
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set(headerRateLimitUsed, "600")
+		w.Header().Set(headerRateLimitRemaining, "0.0")
+		w.Header().Set(headerRateLimitReset, "60")
+		w.Write([]byte(`{"name": "bot"}`))
+	})
+	defer srv.Close()
+
+	if _, err := api.RequestMe(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the budget is used up, so nothing is sent
+	api.RateLimitMode = RateLimitReturnError
+	_, err := api.RequestMe()
+	if rateErr, ok := err.(*RateLimitError); !ok || time.Until(rateErr.Reset) < 59*time.Second {
+		t.Errorf("got %v, want a *RateLimitError", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+
+	api.RateLimitMode = RateLimitIgnore
+	if _, err := api.RequestMe(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+}
@@ -1,9 +1,12 @@
 package api
 
 import (
-	"errors"
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -22,6 +25,21 @@ type RedditAPI struct {
 	Account      *RedditAccount
 	Client       http.Client
 	DebugMode    bool
+
+	// RedditBaseURL and OauthBaseURL are the base URLs for
+	// authentication and API requests respectively. Their paths are
+	// used as a prefix for every endpoint. If nil, reddit's own
+	// hosts are used.
+	RedditBaseURL *url.URL
+	OauthBaseURL  *url.URL
+
+	// RetryPolicy sets how failed requests are retried
+	RetryPolicy RetryPolicy
+
+	// RateLimitMode sets what to do when reddit's rate limit has
+	// been used up
+	RateLimitMode RateLimitMode
+	rateLimiter   rateLimiter
 }
 
 // NewRedditAPI creates a new API with a given ClientID and
@@ -32,6 +50,7 @@ func NewRedditAPI(clientID, clientSecret, userAgent, username string, debugMode
 		clientSecret: clientSecret,
 		UserAgent:    userAgent,
 		DebugMode:    debugMode,
+		RetryPolicy:  DefaultRetryPolicy,
 	}
 	account := RedditAccount{
 		API:      &reddit,
@@ -42,27 +61,42 @@ func NewRedditAPI(clientID, clientSecret, userAgent, username string, debugMode
 	return &reddit
 }
 
+// NewRequest creates a request with the user agent and the
+// appropriate authentication for the host set
 func (api *RedditAPI) NewRequest(method string, u *url.URL, body io.Reader) (*http.Request, error) {
+	return api.NewRequestContext(context.Background(), method, u, body)
+}
+
+// NewRequestContext is like NewRequest but with a context
+func (api *RedditAPI) NewRequestContext(ctx context.Context, method string, u *url.URL, body io.Reader) (*http.Request, error) {
 	// create new request
 	url := u.String()
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
 
-	// set auth
-	switch u.Host {
-	case oauthHost:
-		// if using OAUTH, check token is valid and set bearer
-		// auth header
-		if api.Account.Token.Token == "" {
-			return nil, errors.New("no valid token")
+	// set auth, using the most specific base URL that matches. The
+	// authentication endpoints always use basic auth, as both bases
+	// may point at the same server.
+	redditMatch := matchBaseURL(api.redditBaseURL(), u)
+	oauthMatch := matchBaseURL(api.oauthBaseURL(), u)
+	switch {
+	case api.isAuthEndpoint(u):
+		req.SetBasicAuth(api.ClientID, api.clientSecret)
+	case oauthMatch >= 0 && oauthMatch >= redditMatch:
+		// if using OAUTH, get a valid token (refreshing it if
+		// necessary) and set bearer auth header
+		token, err := api.Account.accessToken(ctx)
+		if err != nil {
+			return nil, err
 		}
-		if time.Now().After(api.Account.Token.Expiry) {
-			return nil, errors.New("token expired")
+		// app-only tokens cannot be used to make changes
+		if token.AppOnly() && method != http.MethodGet {
+			return nil, ErrAppOnlyToken
 		}
-		req.Header.Set("Authorization", fmt.Sprintf("bearer %s", api.Account.Token.Token))
-	case redditHost:
+		req.Header.Set("Authorization", fmt.Sprintf("bearer %s", token.Token))
+	case redditMatch >= 0:
 		// if using reddit, set basic auth
 		req.SetBasicAuth(api.ClientID, api.clientSecret)
 	}
@@ -77,60 +111,125 @@ func (api *RedditAPI) NewRequest(method string, u *url.URL, body io.Reader) (*ht
 // query parameters
 // Don't forget to close the response body
 func (api *RedditAPI) Get(u *url.URL, query url.Values) (*http.Response, error) {
+	return api.GetContext(context.Background(), u, query)
+}
+
+// GetContext is like Get but with a context
+func (api *RedditAPI) GetContext(ctx context.Context, u *url.URL, query url.Values) (*http.Response, error) {
 	// add the GET query
 	u.RawQuery = query.Encode()
 
 	// create new request
-	req, err := api.NewRequest(http.MethodGet, u, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// log request
-	if api.DebugMode {
-		dump, err := httputil.DumpRequest(req, true)
-		if err == nil {
-			logrus.WithFields(logrus.Fields{
-				"request_dump": string(dump),
-			}).Info("sending request")
-		}
-	}
-
-	// do request
-	resp, err := api.Client.Do(req)
+	req, err := api.NewRequestContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// log response
-	if api.DebugMode {
-		dump, err := httputil.DumpResponse(resp, true)
-		if err == nil {
-			logrus.WithFields(logrus.Fields{
-				"response_dump": string(dump),
-			}).Info("response received")
-		}
-	}
-
-	return resp, nil
+	return api.do(req)
 }
 
 // PostForm posts form data to the specified URL with the required
-// authentication
+// authentication. The request is assumed not to be idempotent, so it
+// will only be retried if reddit rejects it with a 429.
 // Don't forget to close the response body
 func (api *RedditAPI) PostForm(u *url.URL, data url.Values) (*http.Response, error) {
+	return api.postForm(context.Background(), u, data, false)
+}
+
+// PostFormContext is like PostForm but with a context
+func (api *RedditAPI) PostFormContext(ctx context.Context, u *url.URL, data url.Values) (*http.Response, error) {
+	return api.postForm(ctx, u, data, false)
+}
+
+// postForm posts form data, marking the request as safe to retry if
+// it is idempotent
+func (api *RedditAPI) postForm(ctx context.Context, u *url.URL, data url.Values, idempotent bool) (*http.Response, error) {
 	// create request body from data
 	body := data.Encode()
 	bodyReader := strings.NewReader(body)
 
 	// create the request
-	req, err := api.NewRequest(http.MethodPost, u, bodyReader)
+	req, err := api.NewRequestContext(ctx, http.MethodPost, u, bodyReader)
 	if err != nil {
 		return nil, err
 	}
 
 	// set content type
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotent {
+		markIdempotent(req)
+	}
+
+	return api.do(req)
+}
+
+// postJSON posts v encoded as JSON, for the few endpoints that don't
+// take form data
+func (api *RedditAPI) postJSON(ctx context.Context, u *url.URL, v interface{}, idempotent bool) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the request
+	req, err := api.NewRequestContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// set content type
+	req.Header.Set("Content-Type", "application/json")
+	if idempotent {
+		markIdempotent(req)
+	}
+
+	return api.do(req)
+}
+
+// do sends a request, retrying it according to the RetryPolicy
+func (api *RedditAPI) do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := api.send(req)
+		delay, retry := api.RetryPolicy.retryDelay(req, resp, err, attempt)
+		if !retry {
+			return resp, err
+		}
+
+		// discard the failed response
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if api.DebugMode {
+			logrus.WithFields(logrus.Fields{
+				"attempt": attempt,
+				"delay":   delay,
+				"error":   err,
+			}).Info("retrying request")
+		}
+		if err := sleepContext(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		// rewind the body
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// send sends a request once, keeping within the rate limit and
+// logging the request and response in debug mode
+func (api *RedditAPI) send(req *http.Request) (*http.Response, error) {
+	// wait for rate limit
+	if err := api.waitRateLimit(req.Context()); err != nil {
+		return nil, err
+	}
 
 	// log request
 	if api.DebugMode {
@@ -148,6 +247,9 @@ func (api *RedditAPI) PostForm(u *url.URL, data url.Values) (*http.Response, err
 		return nil, err
 	}
 
+	// update rate limit
+	api.rateLimiter.update(resp.Header)
+
 	// log response
 	if api.DebugMode {
 		dump, err := httputil.DumpResponse(resp, true)
@@ -160,3 +262,17 @@ func (api *RedditAPI) PostForm(u *url.URL, data url.Values) (*http.Response, err
 
 	return resp, nil
 }
+
+// sleepContext waits for the given duration, returning early with an
+// error if the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,354 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// subreddit relationships
+const (
+	RelBanned          = "banned"
+	RelMuted           = "muted"
+	RelContributor     = "contributor"
+	RelModerator       = "moderator"
+	RelModeratorInvite = "moderator_invite"
+)
+
+// moderator permissions
+const (
+	ModPermAll          = "all"
+	ModPermAccess       = "access"
+	ModPermConfig       = "config"
+	ModPermFlair        = "flair"
+	ModPermMail         = "mail"
+	ModPermPosts        = "posts"
+	ModPermWiki         = "wiki"
+	ModPermChatConfig   = "chat_config"
+	ModPermChatOperator = "chat_operator"
+)
+
+// limits on bans
+const (
+	maxBanDuration  = 999
+	maxBanReasonLen = 100
+	maxBanNoteLen   = 300
+)
+
+// relationshipListings maps relationships to the listings of them
+var relationshipListings = map[string]string{
+	RelBanned:      "banned",
+	RelMuted:       "muted",
+	RelContributor: "contributors",
+	RelModerator:   "moderators",
+}
+
+// BanOptions holds the details of a ban. Any field may be left blank.
+type BanOptions struct {
+	// Duration is the length of the ban in days, or 0 for a permanent
+	// ban
+	Duration int
+	// Reason is the reason for the ban, shown to other moderators
+	Reason string
+	// Note is a note for other moderators
+	Note string
+	// Message is included in the message telling the user they are
+	// banned
+	Message string
+	// Context is the fullname of the post or comment that led to the
+	// ban
+	Context Fullname
+}
+
+// validate checks the options before they are sent
+func (opts *BanOptions) validate() error {
+	if opts.Duration < 0 || opts.Duration > maxBanDuration {
+		return &ValidationError{Field: "duration", Message: fmt.Sprintf("must be between 0 and %d days", maxBanDuration)}
+	}
+	if len([]rune(opts.Reason)) > maxBanReasonLen {
+		return &ValidationError{Field: "ban_reason", Message: fmt.Sprintf("longer than %d characters", maxBanReasonLen)}
+	}
+	if len([]rune(opts.Note)) > maxBanNoteLen {
+		return &ValidationError{Field: "note", Message: fmt.Sprintf("longer than %d characters", maxBanNoteLen)}
+	}
+	if opts.Context != "" {
+		return checkFullname(opts.Context, KindPost, KindComment)
+	}
+	return nil
+}
+
+// permissionString encodes moderator permissions as reddit expects,
+// with no permissions meaning all of them
+func permissionString(permissions []string) string {
+	if len(permissions) == 0 {
+		return "+" + ModPermAll
+	}
+	encoded := []string{"-" + ModPermAll}
+	for _, p := range permissions {
+		if p == ModPermAll {
+			return "+" + ModPermAll
+		}
+		encoded = append(encoded, "+"+p)
+	}
+	return strings.Join(encoded, ",")
+}
+
+// postRelationship sends a request to one of the endpoints that
+// manage a subreddit's relationships. Requests that message the user,
+// such as bans, are not idempotent.
+func (api *RedditAPI) postRelationship(ctx context.Context, endpoint, subreddit string, data url.Values, idempotent bool) error {
+	u := api.GetOauthURL(endpoint, subreddit)
+	data.Set("api_type", "json")
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, idempotent)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response BaseJSONResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return err
+	}
+	return response.Error()
+}
+
+// friend adds a user to one of a subreddit's relationships
+func (api *RedditAPI) friend(ctx context.Context, subreddit, username, rel string, data url.Values) error {
+	data.Set("name", username)
+	data.Set("type", rel)
+	return api.postRelationship(ctx, OauthEndpointFriend, subreddit, data, false)
+}
+
+// unfriend removes a user from one of a subreddit's relationships
+func (api *RedditAPI) unfriend(ctx context.Context, subreddit, username, rel string) error {
+	data := url.Values{
+		"name": {username},
+		"type": {rel},
+	}
+	return api.postRelationship(ctx, OauthEndpointUnfriend, subreddit, data, false)
+}
+
+// BanUser bans a user from a subreddit. opts may be nil for a
+// permanent ban with no details.
+func (api *RedditAPI) BanUser(subreddit, username string, opts *BanOptions) error {
+	return api.BanUserContext(context.Background(), subreddit, username, opts)
+}
+
+// BanUserContext is like BanUser but with a context
+func (api *RedditAPI) BanUserContext(ctx context.Context, subreddit, username string, opts *BanOptions) error {
+	if opts == nil {
+		opts = &BanOptions{}
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	// construct post data
+	data := url.Values{}
+	if opts.Duration > 0 {
+		data.Set("duration", strconv.Itoa(opts.Duration))
+	}
+	if opts.Reason != "" {
+		data.Set("ban_reason", opts.Reason)
+	}
+	if opts.Note != "" {
+		data.Set("note", opts.Note)
+	}
+	if opts.Message != "" {
+		data.Set("ban_message", opts.Message)
+	}
+	if opts.Context != "" {
+		data.Set("ban_context", string(opts.Context))
+	}
+
+	return api.friend(ctx, subreddit, username, RelBanned, data)
+}
+
+// UnbanUser lifts a user's ban from a subreddit
+func (api *RedditAPI) UnbanUser(subreddit, username string) error {
+	return api.UnbanUserContext(context.Background(), subreddit, username)
+}
+
+// UnbanUserContext is like UnbanUser but with a context
+func (api *RedditAPI) UnbanUserContext(ctx context.Context, subreddit, username string) error {
+	return api.unfriend(ctx, subreddit, username, RelBanned)
+}
+
+// MuteUser stops a user from sending modmail to a subreddit. note may
+// be blank.
+func (api *RedditAPI) MuteUser(subreddit, username, note string) error {
+	return api.MuteUserContext(context.Background(), subreddit, username, note)
+}
+
+// MuteUserContext is like MuteUser but with a context
+func (api *RedditAPI) MuteUserContext(ctx context.Context, subreddit, username, note string) error {
+	if len([]rune(note)) > maxBanNoteLen {
+		return &ValidationError{Field: "note", Message: fmt.Sprintf("longer than %d characters", maxBanNoteLen)}
+	}
+
+	// construct post data
+	data := url.Values{}
+	if note != "" {
+		data.Set("note", note)
+	}
+
+	return api.friend(ctx, subreddit, username, RelMuted, data)
+}
+
+// UnmuteUser undoes MuteUser
+func (api *RedditAPI) UnmuteUser(subreddit, username string) error {
+	return api.UnmuteUserContext(context.Background(), subreddit, username)
+}
+
+// UnmuteUserContext is like UnmuteUser but with a context
+func (api *RedditAPI) UnmuteUserContext(ctx context.Context, subreddit, username string) error {
+	return api.unfriend(ctx, subreddit, username, RelMuted)
+}
+
+// AddContributor makes a user an approved submitter
+func (api *RedditAPI) AddContributor(subreddit, username string) error {
+	return api.AddContributorContext(context.Background(), subreddit, username)
+}
+
+// AddContributorContext is like AddContributor but with a context
+func (api *RedditAPI) AddContributorContext(ctx context.Context, subreddit, username string) error {
+	return api.friend(ctx, subreddit, username, RelContributor, url.Values{})
+}
+
+// RemoveContributor undoes AddContributor
+func (api *RedditAPI) RemoveContributor(subreddit, username string) error {
+	return api.RemoveContributorContext(context.Background(), subreddit, username)
+}
+
+// RemoveContributorContext is like RemoveContributor but with a
+// context
+func (api *RedditAPI) RemoveContributorContext(ctx context.Context, subreddit, username string) error {
+	return api.unfriend(ctx, subreddit, username, RelContributor)
+}
+
+// InviteModerator invites a user to moderate a subreddit with the
+// given ModPerm permissions. No permissions means all of them.
+func (api *RedditAPI) InviteModerator(subreddit, username string, permissions []string) error {
+	return api.InviteModeratorContext(context.Background(), subreddit, username, permissions)
+}
+
+// InviteModeratorContext is like InviteModerator but with a context
+func (api *RedditAPI) InviteModeratorContext(ctx context.Context, subreddit, username string, permissions []string) error {
+	// construct post data
+	data := url.Values{
+		"permissions": {permissionString(permissions)},
+	}
+
+	return api.friend(ctx, subreddit, username, RelModeratorInvite, data)
+}
+
+// RevokeModeratorInvite withdraws an invitation to moderate
+func (api *RedditAPI) RevokeModeratorInvite(subreddit, username string) error {
+	return api.RevokeModeratorInviteContext(context.Background(), subreddit, username)
+}
+
+// RevokeModeratorInviteContext is like RevokeModeratorInvite but with
+// a context
+func (api *RedditAPI) RevokeModeratorInviteContext(ctx context.Context, subreddit, username string) error {
+	return api.unfriend(ctx, subreddit, username, RelModeratorInvite)
+}
+
+// AcceptModeratorInvite accepts the account's invitation to moderate
+// a subreddit
+func (api *RedditAPI) AcceptModeratorInvite(subreddit string) error {
+	return api.AcceptModeratorInviteContext(context.Background(), subreddit)
+}
+
+// AcceptModeratorInviteContext is like AcceptModeratorInvite but with
+// a context
+func (api *RedditAPI) AcceptModeratorInviteContext(ctx context.Context, subreddit string) error {
+	return api.postRelationship(ctx, OauthEndpointAcceptModInvite, subreddit, url.Values{}, true)
+}
+
+// RemoveModerator removes a moderator from a subreddit
+func (api *RedditAPI) RemoveModerator(subreddit, username string) error {
+	return api.RemoveModeratorContext(context.Background(), subreddit, username)
+}
+
+// RemoveModeratorContext is like RemoveModerator but with a context
+func (api *RedditAPI) RemoveModeratorContext(ctx context.Context, subreddit, username string) error {
+	return api.unfriend(ctx, subreddit, username, RelModerator)
+}
+
+// SetModeratorPermissions changes the ModPerm permissions of a
+// moderator, or of a pending invitation if invite is set. No
+// permissions means all of them.
+func (api *RedditAPI) SetModeratorPermissions(subreddit, username string, permissions []string, invite bool) error {
+	return api.SetModeratorPermissionsContext(context.Background(), subreddit, username, permissions, invite)
+}
+
+// SetModeratorPermissionsContext is like SetModeratorPermissions but
+// with a context
+func (api *RedditAPI) SetModeratorPermissionsContext(ctx context.Context, subreddit, username string, permissions []string, invite bool) error {
+	rel := RelModerator
+	if invite {
+		rel = RelModeratorInvite
+	}
+
+	// construct post data
+	data := url.Values{
+		"name":        {username},
+		"type":        {rel},
+		"permissions": {permissionString(permissions)},
+	}
+
+	return api.postRelationship(ctx, OauthEndpointSetPermissions, subreddit, data, true)
+}
+
+// RelationshipIterator iterates over the users in a subreddit's
+// relationship list, fetching more pages as needed. Call Next before
+// each Relationship, and check Err once Next returns false.
+type RelationshipIterator struct {
+	it  *ThingIterator
+	rel *Relationship
+}
+
+// Next advances to the next user, returning false when there are no
+// more users or an error occurred
+func (r *RelationshipIterator) Next() bool {
+	for r.it.Next() {
+		if rel, ok := r.it.Thing().Relationship(); ok {
+			r.rel = rel
+			return true
+		}
+	}
+	r.rel = nil
+	return false
+}
+
+// Relationship returns the current user's entry
+func (r *RelationshipIterator) Relationship() *Relationship {
+	return r.rel
+}
+
+// Err returns the error that stopped the iterator, if any
+func (r *RelationshipIterator) Err() error {
+	return r.it.Err()
+}
+
+// RequestRelationships returns an iterator over the users with a
+// relationship to a subreddit, with rel being RelBanned, RelMuted,
+// RelContributor or RelModerator. opts may be nil.
+func (api *RedditAPI) RequestRelationships(subreddit, rel string, opts *ListingOptions) *RelationshipIterator {
+	return api.RequestRelationshipsContext(context.Background(), subreddit, rel, opts)
+}
+
+// RequestRelationshipsContext is like RequestRelationships but with a
+// context
+func (api *RedditAPI) RequestRelationshipsContext(ctx context.Context, subreddit, rel string, opts *ListingOptions) *RelationshipIterator {
+	location, ok := relationshipListings[rel]
+	it := api.newThingIterator(ctx, api.GetOauthURL(OauthEndpointModListing, subreddit, location), url.Values{}, opts)
+	if !ok {
+		it.err = &ValidationError{Field: "rel", Message: fmt.Sprintf("%q is not a relationship", rel)}
+	}
+	return &RelationshipIterator{it: it}
+}
@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestBanUser(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	opts := &reddit.BanOptions{Duration: 3, Reason: "spam", Message: "please stop"}
+	if err := api.BanUser("test", "other", opts); err != nil {
+		t.Fatal(err)
+	}
+	banned := api.RequestRelationships("test", reddit.RelBanned, nil)
+	var got []*reddit.Relationship
+	for banned.Next() {
+		got = append(got, banned.Relationship())
+	}
+	if err := banned.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// whole days left are counted, as reddit does
+	if len(got) != 1 || got[0].Name != "other" || got[0].DaysLeft == nil || *got[0].DaysLeft != 2 {
+		t.Fatalf("got %+v", got)
+	}
+
+	if err := api.UnbanUser("test", "other"); err != nil {
+		t.Fatal(err)
+	}
+	banned = api.RequestRelationships("test", reddit.RelBanned, nil)
+	if banned.Next() || banned.Err() != nil {
+		t.Errorf("banned list not empty after unbanning: %v", banned.Err())
+	}
+
+	// only moderators can ban
+	other := login(t, s, "other")
+	checkStatus(t, other.BanUser("test", "bot", nil), http.StatusForbidden)
+}
+
+func TestInvalidListings(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	var validationErr *reddit.ValidationError
+	rels := api.RequestRelationships("test", "enemies", nil)
+	if rels.Next() || !errors.As(rels.Err(), &validationErr) || validationErr.Field != "rel" {
+		t.Errorf("got %v, want a *ValidationError for rel", rels.Err())
+	}
+	queue := api.RequestModListing("test", "inbox", nil)
+	if queue.Next() || !errors.As(queue.Err(), &validationErr) || validationErr.Field != "location" {
+		t.Errorf("got %v, want a *ValidationError for location", queue.Err())
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -14,6 +13,10 @@ import (
 type FloatTime time.Time
 
 func (ft *FloatTime) UnmarshalJSON(data []byte) error {
+	// unset times are null
+	if string(data) == "null" {
+		return nil
+	}
 	// ensure it's not a bool first -- reddit is dodgy like that
 	_, err := strconv.ParseBool(string(data))
 	if err == nil {
@@ -30,16 +33,28 @@ func (ft *FloatTime) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// statusRecorder is a response that keeps the HTTP status it was
+// decoded from
+type statusRecorder interface {
+	recordStatus(status int)
+}
+
 // BaseResponse stores "error" and "message" and provides a function
 // for validating a response based on these
 type BaseResponse struct {
 	Err     int64  `json:"error"`
 	Message string `json:"message"`
+	status  int
 }
 
+func (r *BaseResponse) recordStatus(status int) {
+	r.status = status
+}
+
+// Error returns an *HTTPStatusError if reddit reported one
 func (r *BaseResponse) Error() error {
 	if r.Err != 0 {
-		return errors.New(fmt.Sprintf("reddit error '%v': %s", r.Err, r.Message))
+		return statusError(r.Err, r.status, r.Message)
 	}
 	return nil
 }
@@ -47,38 +62,37 @@ func (r *BaseResponse) Error() error {
 // BaseJSONResponse is for the JSON API and provides the necessary
 // Error function for it
 type BaseJSONResponse struct {
-	JSON    map[string][][]string `json:"json"`
-	Message string                `json:"message"` // indicates a different failure
+	JSON    JSONErrors `json:"json"`
+	Err     int64      `json:"error"`   // set along with message
+	Message string     `json:"message"` // indicates a different failure
+	status  int
 }
 
-func (r *BaseJSONResponse) Error() error {
-	if r.Message != "" {
-		return errors.New(fmt.Sprintf("reddit error: %s", r.Message))
-	}
-	errs, ok := r.JSON["errors"]
-	if !ok {
-		// no errors
-		return nil
-	}
+func (r *BaseJSONResponse) recordStatus(status int) {
+	r.status = status
+}
 
-	// check how many errors occurred
-	if len(errs) == 0 {
-		return nil
-	}
-	if len(errs) > 1 {
-		return errors.New("SetStylesheet: too many errors")
-	}
+// JSONErrors holds the errors returned by the JSON API as [code,
+// message, field] triples
+type JSONErrors struct {
+	Errors    [][]string `json:"errors"`
+	RateLimit float64    `json:"ratelimit"` // seconds until reset
+}
 
-	// if only one error, construct a proper error
-	errString := strings.Join(errs[0], " ")
-	return errors.New(fmt.Sprintf("SetStylesheet: %s", errString))
+// Error returns an *APIError containing every error reported, or an
+// *HTTPStatusError if the request failed outright
+func (r *BaseJSONResponse) Error() error {
+	if err := statusError(r.Err, r.status, r.Message); err != nil {
+		return err
+	}
+	return newAPIError(r.JSON.Errors, r.JSON.RateLimit)
 }
 
 // MeResponse is the response from a Me query
 type MeResponse struct {
 	BaseResponse
 
-	Username         string    `json:"reddit_bot"`
+	Username         string    `json:"name"`
 	CommentKarma     int       `json:"comment_karma"`
 	LinkKarma        int       `json:"link_karma"`
 	Created          FloatTime `json:"created"`
@@ -120,18 +134,14 @@ type SetStylesheetResponse struct {
 
 type intermediateSubmitPostResponse struct {
 	JSON submitPostJSON
+	BaseResponse
 }
 
 func (r *intermediateSubmitPostResponse) Error() error {
-	errs := r.JSON.Errors
-	switch len(errs) {
-	case 0:
-		return nil
-	case 1:
-		return errors.New(fmt.Sprintf("SubmitPost: %s", strings.Join(errs[0], " ")))
-	default:
-		return errors.New("SubmitPost: too many errors")
+	if err := statusError(r.Err, r.status, r.Message); err != nil {
+		return err
 	}
+	return newAPIError(r.JSON.Errors, r.JSON.RateLimit)
 }
 
 func (r *intermediateSubmitPostResponse) GetData() *SubmitPostData {
@@ -139,15 +149,39 @@ func (r *intermediateSubmitPostResponse) GetData() *SubmitPostData {
 }
 
 type submitPostJSON struct {
-	Errors [][]string     `json:"errors"`
-	Data   SubmitPostData `json:"data"`
+	JSONErrors
+	Data SubmitPostData `json:"data"`
 }
 
 type SubmitPostData struct {
-	URL    string `json:"url"`
-	Drafts int    `json:"drafts_count"`
-	ID     string `json:"id"`
-	Name   string `json:"name"`
+	URL    string   `json:"url"`
+	Drafts int      `json:"drafts_count"`
+	ID     string   `json:"id"`
+	Name   Fullname `json:"name"`
+	// media posts are processed after submission, so reddit may
+	// only return a websocket to watch for the post instead of an
+	// ID
+	WebsocketURL      string `json:"websocket_url"`
+	UserSubmittedPage string `json:"user_submitted_page"`
+}
+
+type mediaAssetResponse struct {
+	BaseResponse
+
+	Args  mediaUploadLease `json:"args"`
+	Asset struct {
+		AssetID      string `json:"asset_id"`
+		WebsocketURL string `json:"websocket_url"`
+	} `json:"asset"`
+}
+
+// mediaUploadLease is where and how to upload a media file
+type mediaUploadLease struct {
+	Action string `json:"action"`
+	Fields []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"fields"`
 }
 
 type requestStickyResponse struct {
@@ -160,92 +194,228 @@ type requestContestModeResponse struct {
 
 /* Post Handling */
 
-// All of this is a bit of a shit-show -- it should probably get
-// refactored at some point, but for now it works
+// PostResponse is a post (t3)
+type PostResponse struct {
+	Subreddit     string       `json:"subreddit"`
+	Saved         bool         `json:"saved"`
+	GildCount     int          `json:"gilded"`
+	Hidden        bool         `json:"hidden"`
+	Likes         *bool        `json:"likes"` // nil if not voted on
+	Downvotes     int64        `json:"downs"`
+	Name          Fullname     `json:"name"`
+	ID            string       `json:"id"`
+	Title         string       `json:"title"`
+	Permalink     string       `json:"permalink"`
+	Quarantined   bool         `json:"quarantine"`
+	SubredditType string       `json:"subreddit_type"`
+	Upvotes       int64        `json:"ups"`
+	AuthorName    Fullname     `json:"author_fullname"`
+	CommentCount  int64        `json:"num_comments"`
+	Score         int64        `json:"score"`
+	Edited        FloatTime    `json:"edited"`
+	IsSelf        bool         `json:"is_self"`
+	Archived      bool         `json:"archived"`
+	NSFW          bool         `json:"over_18"`
+	Removed       bool         `json:"removed"`
+	Spoiler       bool         `json:"spoiler"`
+	Locked        bool         `json:"locked"`
+	SubredditName Fullname     `json:"subreddit_id"`
+	Author        string       `json:"author"`
+	ContestMode   bool         `json:"contest_mode"`
+	Approved      bool         `json:"approved"`
+	IgnoreReports bool         `json:"ignore_reports"`
+	NumReports    int          `json:"num_reports"`
+	UserReports   []UserReport `json:"user_reports"`
+	ModReports    []ModReport  `json:"mod_reports"`
+	Distinguished string       `json:"distinguished"`
+	Stickied      bool         `json:"stickied"`
+	SuggestedSort string       `json:"suggested_sort"`
+	URL           string       `json:"url"`
+	IsVideo       bool         `json:"is_video"`
+	IsGallery     bool         `json:"is_gallery"`
+	GalleryData   *GalleryData `json:"gallery_data"`
+	PollData      *PollData    `json:"poll_data"`
+	FlairText     string       `json:"link_flair_text"`
+	FlairID       string       `json:"link_flair_template_id"`
+	EventStart    FloatTime    `json:"event_start"`
+	EventEnd      FloatTime    `json:"event_end"`
+	CreatedUTC    FloatTime    `json:"created_utc"`
+	Body          string       `json:"selftext"`
+	Replies       []CommentResponse
+	// More holds stubs for top-level comments that were not
+	// included. See ExpandMoreComments.
+	More []*MoreChildren `json:"-"`
+}
 
-type postListingIntermediary struct {
-	Data postIntermediary1 `json:"data"`
+// GalleryData lists the images in a gallery post
+type GalleryData struct {
+	Items []GalleryDataItem `json:"items"`
 }
 
-type postIntermediary1 struct {
-	Children []postIntermediary2 `json:"children"`
+// GalleryDataItem is an image in a gallery post
+type GalleryDataItem struct {
+	MediaID     string `json:"media_id"`
+	ID          int64  `json:"id"`
+	Caption     string `json:"caption"`
+	OutboundURL string `json:"outbound_url"`
 }
 
-type postIntermediary2 struct {
-	Data PostResponse `json:"data"`
+// PollData holds the options and results of a poll post. Vote counts
+// are only shown once voting has ended or the user has voted.
+type PollData struct {
+	Options        []PollOption `json:"options"`
+	TotalVoteCount int64        `json:"total_vote_count"`
+	// VotingEndTimestamp is in milliseconds. See VotingEnd.
+	VotingEndTimestamp int64 `json:"voting_end_timestamp"`
+	// UserSelection is the ID of the option the user voted for,
+	// if any
+	UserSelection string `json:"user_selection"`
 }
 
-type PostResponse struct {
-	Subreddit     string    `json:"subreddit"`
-	Saved         bool      `json:"saved"`
-	GildCount     int       `json:"gilded"`
-	Hidden        bool      `json:"hidden"`
-	Downvotes     int64     `json:"downs"`
-	Name          string    `json:"name"`
-	ID            string    `json:"id"`
-	Quarantined   bool      `json:"quarantine"`
-	SubredditType string    `json:"subreddit_type"`
-	Upvotes       int64     `json:"ups"`
-	AuthorName    string    `json:"author_fullname"`
-	CommentCount  int64     `json:"num_comments"`
-	Score         int64     `json:"score"`
-	Edited        FloatTime `json:"edited"`
-	IsSelf        bool      `json:"is_self"`
-	Archived      bool      `json:"archived"`
-	NSFW          bool      `json:"over_18"`
-	Removed       bool      `json:"removed"`
-	Spoiler       bool      `json:"spoiler"`
-	Locked        bool      `json:"locked"`
-	SubredditName string    `json:"subreddit_id"`
-	Author        string    `json:"author"`
-	ContestMode   bool      `json:"contest_mode"`
-	Approved      bool      `json:"approved"`
-	Stickied      bool      `json:"stickied"`
-	URL           string    `json:"url"`
-	CreatedUTC    FloatTime `json:"created_utc"`
-	Body          string    `json:"selftext"`
-	Replies       []CommentResponse
+// VotingEnd returns the time voting ends
+func (p *PollData) VotingEnd() time.Time {
+	return time.Unix(0, p.VotingEndTimestamp*int64(time.Millisecond))
 }
 
-type commentListingIntermediary struct {
-	Data commentIntermediary1 `json:"data"`
+// Option returns the option with the given ID
+func (p *PollData) Option(id string) (*PollOption, bool) {
+	for i := range p.Options {
+		if p.Options[i].ID == id {
+			return &p.Options[i], true
+		}
+	}
+	return nil, false
 }
 
-type commentIntermediary1 struct {
-	Children []commentIntermediary2 `json:"children"`
+// PollOption is an option in a poll. VoteCount is nil while the
+// results are hidden.
+type PollOption struct {
+	ID        string `json:"id"`
+	Text      string `json:"text"`
+	VoteCount *int64 `json:"vote_count"`
 }
 
-type commentIntermediary2 struct {
-	Data CommentResponse `json:"data"`
+// UserReport is a reason users gave for reporting a post or comment,
+// only visible to moderators
+type UserReport struct {
+	Reason string
+	Count  int
 }
 
+// UnmarshalJSON decodes a report from reddit's [reason, count, ...]
+// array
+func (r *UserReport) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) < 2 {
+		return errors.New(fmt.Sprintf("user report has %d fields", len(fields)))
+	}
+	// the reason is null for reports without one
+	var reason *string
+	if err := json.Unmarshal(fields[0], &reason); err != nil {
+		return err
+	}
+	if reason != nil {
+		r.Reason = *reason
+	}
+	return json.Unmarshal(fields[1], &r.Count)
+}
+
+// ModReport is a report made by a moderator
+type ModReport struct {
+	Reason    string
+	Moderator string
+}
+
+// UnmarshalJSON decodes a report from reddit's [reason, moderator]
+// array
+func (r *ModReport) UnmarshalJSON(data []byte) error {
+	var fields []string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) < 2 {
+		return errors.New(fmt.Sprintf("mod report has %d fields", len(fields)))
+	}
+	r.Reason, r.Moderator = fields[0], fields[1]
+	return nil
+}
+
+// CommentResponse is a comment (t1)
 type CommentResponse struct {
 	Subreddit      string          `json:"subreddit"`
 	Saved          bool            `json:"saved"`
+	Likes          *bool           `json:"likes"` // nil if not voted on
 	GildCount      int             `json:"gilded"`
 	Downvotes      int64           `json:"downs"`
-	Name           string          `json:"name"`
+	Name           Fullname        `json:"name"`
+	ID             string          `json:"id"`
+	LinkID         Fullname        `json:"link_id"`
+	Permalink      string          `json:"permalink"`
 	SubredditType  string          `json:"subreddit_type"`
 	Upvotes        int64           `json:"ups"`
-	AuthorName     string          `json:"author_fullname"`
+	AuthorName     Fullname        `json:"author_fullname"`
 	Score          int64           `json:"score"`
 	Edited         FloatTime       `json:"edited"`
 	Archived       bool            `json:"archived"`
 	Removed        bool            `json:"removed"`
 	Spoiler        bool            `json:"spoiler"`
 	Locked         bool            `json:"locked"`
-	SubredditName  string          `json:"subreddit_id"`
+	SubredditName  Fullname        `json:"subreddit_id"`
 	Author         string          `json:"author"`
 	ContestMode    bool            `json:"contest_mode"`
 	Approved       bool            `json:"approved"`
+	IgnoreReports  bool            `json:"ignore_reports"`
+	NumReports     int             `json:"num_reports"`
+	UserReports    []UserReport    `json:"user_reports"`
+	ModReports     []ModReport     `json:"mod_reports"`
+	Distinguished  string          `json:"distinguished"`
 	Stickied       bool            `json:"stickied"`
 	CreatedUTC     FloatTime       `json:"created_utc"`
 	Body           string          `json:"body"`
-	ParentID       string          `json:"parent_id"`
+	ParentID       Fullname        `json:"parent_id"`
+	Depth          int             `json:"depth"`
 	RepliesListing json.RawMessage `json:"replies"`
 	Replies        []*CommentResponse
+	// More holds stubs for replies that were not included. See
+	// ExpandMoreComments.
+	More []*MoreChildren `json:"-"`
+}
+
+// MoreChildren is a "more" stub that reddit inserts in place of
+// comments left out of large threads. If Children is empty, the
+// thread continues too deep to be included and has to be fetched
+// from the parent comment instead.
+type MoreChildren struct {
+	Count    int      `json:"count"`
+	Name     Fullname `json:"name"`
+	ID       string   `json:"id"`
+	ParentID Fullname `json:"parent_id"`
+	Depth    int      `json:"depth"`
+	Children []string `json:"children"`
 }
 
+// splitComments separates the comments in a listing from the "more"
+// stubs
+func splitComments(children []Thing) ([]*CommentResponse, []*MoreChildren) {
+	var (
+		comments []*CommentResponse
+		more     []*MoreChildren
+	)
+	for i := range children {
+		if comment, ok := children[i].Comment(); ok {
+			comments = append(comments, comment)
+		} else if stub, ok := children[i].More(); ok {
+			more = append(more, stub)
+		}
+	}
+	return comments, more
+}
+
+// DecodeReplies decodes the replies listing into Replies and More.
+// This is done automatically when a comment is decoded as a Thing.
 func (parentComment *CommentResponse) DecodeReplies() error {
 	// empty the RepliesListing when done to free up some memory
 	// if nothing went wrong
@@ -257,30 +427,55 @@ func (parentComment *CommentResponse) DecodeReplies() error {
 		return
 	}()
 
-	if string(parentComment.RepliesListing) == `""` {
+	if len(parentComment.RepliesListing) == 0 || string(parentComment.RepliesListing) == `""` {
 		// no replies to decode
 		failed = false
 		return nil
 	}
-	// unmarshal the replies listing into a comment listing
-	var commentListing commentListingIntermediary
-	err := json.Unmarshal(parentComment.RepliesListing, &commentListing)
+	// unmarshal the replies into a listing
+	var replies Thing
+	err := json.Unmarshal(parentComment.RepliesListing, &replies)
 	if err != nil {
 		return err
 	}
-
-	for _, comment := range commentListing.Data.Children {
-		parentComment.Replies = append(parentComment.Replies, &comment.Data)
-		err = comment.Data.DecodeReplies()
-		if err != nil {
-			return err
-		}
+	listing, ok := replies.Listing()
+	if !ok {
+		return errors.New(fmt.Sprintf("unexpected kind for replies: %s", replies.Kind))
 	}
 
+	comments, more := splitComments(listing.Children)
+	parentComment.Replies = append(parentComment.Replies, comments...)
+	parentComment.More = append(parentComment.More, more...)
+
 	failed = false
 	return nil
 }
 
+// thingsResponse is the response from JSON API endpoints that return
+// the things they created or changed
+type thingsResponse struct {
+	JSON    thingsJSON `json:"json"`
+	Err     int64      `json:"error"`
+	Message string     `json:"message"`
+}
+
+// Error checks the response in the same way as a BaseJSONResponse
+func (r *thingsResponse) Error() error {
+	base := BaseJSONResponse{
+		JSON:    r.JSON.JSONErrors,
+		Err:     r.Err,
+		Message: r.Message,
+	}
+	return base.Error()
+}
+
+type thingsJSON struct {
+	JSONErrors
+	Data struct {
+		Things []Thing `json:"things"`
+	} `json:"data"`
+}
+
 // CommentsByScore implements a sort.Interface for sorting comments by
 // score, from lowest to highest
 type CommentsByScore []CommentResponse
@@ -301,6 +496,10 @@ type requestRemovePostResponse struct {
 	BaseResponse
 }
 
+type deleteResponse struct {
+	BaseJSONResponse
+}
+
 // ComposeMessageResponse is the response from composing a message
 type ComposeMessageResponse struct {
 	BaseJSONResponse
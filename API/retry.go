@@ -0,0 +1,146 @@
+package api
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy determines how requests that fail with a network error,
+// a 429 or a 5xx response are retried. Requests that are not
+// idempotent, such as submitting a post, are only retried after a
+// 429, as reddit will not have acted on them.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the
+	// first. Values below 2 disable retrying.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It is doubled
+	// for each further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. A Retry-After
+	// longer than this is not waited for.
+	MaxDelay time.Duration
+	// Jitter is the fraction of each delay that is randomised,
+	// between 0 and 1
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the retry policy set by NewRedditAPI
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+// idempotencyHeader marks a POST request as safe to retry. This
+// follows the same convention as net/http; the header is not sent if
+// its value is nil.
+const idempotencyHeader = "X-Idempotency-Key"
+
+// markIdempotent marks a request as safe to retry
+func markIdempotent(req *http.Request) {
+	req.Header[idempotencyHeader] = nil
+}
+
+// isIdempotent reports whether a request can be safely sent more than
+// once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, ok := req.Header[idempotencyHeader]
+	return ok
+}
+
+// retryDelay decides whether a request should be retried after the
+// given attempt, and how long to wait before doing so
+func (p *RetryPolicy) retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	// the body must be rewound to send the request again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false
+	}
+
+	// the caller has given up
+	if req.Context().Err() != nil {
+		return 0, false
+	}
+
+	if err != nil {
+		// our own rate limiter is not a transient failure
+		if _, ok := err.(*RateLimitError); ok {
+			return 0, false
+		}
+		// the request may have reached reddit
+		if !isIdempotent(req) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		// reddit rejects these without acting on them
+		return p.retryAfter(resp, attempt)
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !isIdempotent(req) {
+			return 0, false
+		}
+		return p.retryAfter(resp, attempt)
+	}
+
+	return 0, false
+}
+
+// retryAfter returns the delay from the Retry-After header, falling
+// back to the backoff delay
+func (p *RetryPolicy) retryAfter(resp *http.Response, attempt int) (time.Duration, bool) {
+	header := resp.Header.Get("Retry-After")
+	if header == "" {
+		return p.backoff(attempt), true
+	}
+
+	var delay time.Duration
+	if seconds, err := strconv.Atoi(header); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(header); err == nil {
+		delay = time.Until(t)
+	} else {
+		return p.backoff(attempt), true
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return 0, false
+	}
+	return delay, true
+}
+
+// backoff returns the exponential backoff delay for an attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	// randomly shorten the delay so that clients don't retry in
+	// lockstep
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+	return delay
+}
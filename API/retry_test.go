@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := p.backoff(attempt + 1); got != want {
+			t.Errorf("attempt %d: got %s, want %s", attempt+1, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.backoff(2); got < time.Second || got > 2*time.Second {
+			t.Fatalf("got %s with jitter, want between 1s and 2s", got)
+		}
+	}
+}
+
+// newRetryAPI returns an API whose server fails the first failures
+// requests other than for a token with status, counting the requests
+func newRetryAPI(t *testing.T, status, failures int32, requests *int32) (func(), *RedditAPI) {
+	srv, api := newTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(requests, 1) <= failures {
+			w.WriteHeader(int(status))
+			return
+		}
+		w.Write([]byte(`{"json": {"errors": []}, "name": "bot"}`))
+	})
+	api.RetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	return srv.Close, api
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	var requests int32
+	closeServer, api := newRetryAPI(t, http.StatusServiceUnavailable, 2, &requests)
+	defer closeServer()
+
+	if _, err := api.RequestMe(); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var requests int32
+	closeServer, api := newRetryAPI(t, http.StatusBadGateway, 5, &requests)
+	defer closeServer()
+
+	_, err := api.RequestMe()
+	if statusErr, ok := err.(*HTTPStatusError); !ok || statusErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("got %v, want a 502 *HTTPStatusError", err)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+}
+
+func TestNoRetryWithSideEffects(t *testing.T) {
+	var requests int32
+	closeServer, api := newRetryAPI(t, http.StatusServiceUnavailable, 1, &requests)
+	defer closeServer()
+
+	// removing a post adds to the mod log, so a 5xx may mean it was
+	// done
+	if err := api.RequestRemovePost("t3_abc", false); err == nil {
+		t.Error("remove was retried after a 503")
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestRetryTooManyRequests(t *testing.T) {
+	var requests int32
+	closeServer, api := newRetryAPI(t, http.StatusTooManyRequests, 1, &requests)
+	defer closeServer()
+
+	// reddit has not acted on a request it rejected with a 429
+	if err := api.RequestRemovePost("t3_abc", false); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2", requests)
+	}
+}
+
+func TestNoRetryBan(t *testing.T) {
+	var requests int32
+	closeServer, api := newRetryAPI(t, http.StatusServiceUnavailable, 1, &requests)
+	defer closeServer()
+
+	// the user may already have been sent the ban message
+	if err := api.BanUser("test", "other", nil); err == nil {
+		t.Error("ban was retried after a 503")
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestNoRetryModmailMute(t *testing.T) {
+	var requests int32
+	closeServer, api := newRetryAPI(t, http.StatusServiceUnavailable, 1, &requests)
+	defer closeServer()
+
+	if _, err := api.MuteModmailUser("abc", ModmailMute3Days); err == nil {
+		t.Error("mute was retried after a 503")
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
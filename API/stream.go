@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaults for streams
+const (
+	defaultStreamMinInterval  = 5 * time.Second
+	defaultStreamMaxInterval  = 2 * time.Minute
+	defaultStreamSeenCapacity = 1000
+)
+
+// StreamOptions controls how a stream polls for new items
+type StreamOptions struct {
+	// MinInterval and MaxInterval bound the time between polls. The
+	// interval grows while nothing new is found and drops back to
+	// MinInterval when there is activity. It is also stretched to
+	// keep within the rate limit budget.
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// SkipExisting skips the items that already exist when the
+	// stream starts, so that only new items are delivered
+	SkipExisting bool
+	// SeenCapacity is the number of recent fullnames remembered to
+	// avoid delivering an item twice. It is at least the number of
+	// items fetched by each poll, 100.
+	SeenCapacity int
+	// OnError is called when a poll fails. The stream carries on
+	// regardless, unless the stream's arguments are invalid, when it
+	// is given a *ValidationError and the stream stops. If nil,
+	// errors are logged.
+	OnError func(error)
+}
+
+// withDefaults returns a copy of the options with unset fields
+// defaulted
+func (opts *StreamOptions) withDefaults() StreamOptions {
+	var o StreamOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.MinInterval <= 0 {
+		o.MinInterval = defaultStreamMinInterval
+	}
+	if o.MaxInterval < o.MinInterval {
+		o.MaxInterval = defaultStreamMaxInterval
+		if o.MaxInterval < o.MinInterval {
+			o.MaxInterval = o.MinInterval
+		}
+	}
+	if o.SeenCapacity <= 0 {
+		o.SeenCapacity = defaultStreamSeenCapacity
+	}
+	// anything less would forget items still in the listing
+	if o.SeenCapacity < maxListingLimit {
+		o.SeenCapacity = maxListingLimit
+	}
+	if o.OnError == nil {
+		o.OnError = func(err error) {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Warn("stream poll failed")
+		}
+	}
+	return o
+}
+
+// seenSet remembers a fixed number of recent fullnames, forgetting
+// the oldest first
+type seenSet struct {
+	names map[Fullname]bool
+	order []Fullname
+	next  int
+}
+
+func newSeenSet(capacity int) *seenSet {
+	return &seenSet{
+		names: map[Fullname]bool{},
+		order: make([]Fullname, capacity),
+	}
+}
+
+// add records a fullname, returning false if it was already seen
+func (s *seenSet) add(name Fullname) bool {
+	if s.names[name] {
+		return false
+	}
+	if old := s.order[s.next]; old != "" {
+		delete(s.names, old)
+	}
+	s.order[s.next] = name
+	s.next = (s.next + 1) % len(s.order)
+	s.names[name] = true
+	return true
+}
+
+// StreamSubmissions delivers new posts from the given subreddits,
+// oldest first, until the context is cancelled. The channel is closed
+// when the stream stops, which is straight away if no subreddits are
+// given or one is blank. opts may be nil.
+func (api *RedditAPI) StreamSubmissions(ctx context.Context, opts *StreamOptions, subreddits ...string) <-chan *PostResponse {
+	err := validateSubreddits(subreddits)
+	u := api.GetOauthURL(OauthEndpointSubredditListing, strings.Join(subreddits, "+"), SortNew)
+	posts := make(chan *PostResponse)
+
+	go func() {
+		defer close(posts)
+		if err != nil {
+			opts.withDefaults().OnError(err)
+			return
+		}
+		api.stream(ctx, opts, u, nil, func(thing *Thing) (Fullname, interface{}, bool) {
+			post, ok := thing.Post()
+			return thing.Fullname(), post, ok
+		}, func(item interface{}) bool {
+			select {
+			case posts <- item.(*PostResponse):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return posts
+}
+
+// StreamComments delivers new comments from the given subreddits,
+// oldest first, until the context is cancelled. The channel is closed
+// when the stream stops, which is straight away if no subreddits are
+// given or one is blank. opts may be nil.
+func (api *RedditAPI) StreamComments(ctx context.Context, opts *StreamOptions, subreddits ...string) <-chan *CommentResponse {
+	err := validateSubreddits(subreddits)
+	u := api.GetOauthURL(OauthEndpointSubredditComments, strings.Join(subreddits, "+"))
+	comments := make(chan *CommentResponse)
+
+	go func() {
+		defer close(comments)
+		if err != nil {
+			opts.withDefaults().OnError(err)
+			return
+		}
+		api.stream(ctx, opts, u, nil, func(thing *Thing) (Fullname, interface{}, bool) {
+			comment, ok := thing.Comment()
+			return thing.Fullname(), comment, ok
+		}, func(item interface{}) bool {
+			select {
+			case comments <- item.(*CommentResponse):
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	return comments
+}
+
+// validateSubreddits checks that there is at least one subreddit to
+// stream and that none of them are blank
+func validateSubreddits(subreddits []string) error {
+	if len(subreddits) == 0 {
+		return &ValidationError{Field: "subreddits", Message: "no subreddits given"}
+	}
+	for i, name := range subreddits {
+		if strings.TrimSpace(name) == "" {
+			return &ValidationError{Field: "subreddits", Message: fmt.Sprintf("subreddit %d is blank", i)}
+		}
+	}
+	return nil
+}
+
+// stream polls the newest page of the listing at u until the context
+// is done. query holds any extra parameters for the endpoint. decode
+// returns the fullname and decoded item for a child, or false if it
+// should be ignored. deliver is called with each new item, oldest
+// first, and returns false if the stream should stop.
+func (api *RedditAPI) stream(ctx context.Context, opts *StreamOptions, u *url.URL, query url.Values, decode func(*Thing) (Fullname, interface{}, bool), deliver func(interface{}) bool) {
+	o := opts.withDefaults()
+	seen := newSeenSet(o.SeenCapacity)
+	interval := o.MinInterval
+	first := true
+
+	for {
+		children, err := api.pollListing(ctx, u, query)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			o.OnError(err)
+			interval *= 2
+		} else {
+			found := false
+			// the listing is newest first
+			for i := len(children) - 1; i >= 0; i-- {
+				fullname, item, ok := decode(children[i])
+				if !ok || !seen.add(fullname) {
+					continue
+				}
+				if first && o.SkipExisting {
+					continue
+				}
+				found = true
+				if !deliver(item) {
+					return
+				}
+			}
+
+			// poll faster while there is activity
+			if found {
+				interval = o.MinInterval
+			} else if !first {
+				interval *= 2
+			}
+			first = false
+		}
+
+		if interval > o.MaxInterval {
+			interval = o.MaxInterval
+		}
+
+		// don't use more than a fair share of the remaining
+		// rate limit budget
+		wait := interval
+		if limit := api.RateLimit(); time.Now().Before(limit.Reset) {
+			share := time.Until(limit.Reset) / time.Duration(limit.Remaining+1)
+			if share > wait {
+				wait = share
+			}
+		}
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return
+		}
+	}
+}
+
+// pollListing fetches the newest page of a listing
+func (api *RedditAPI) pollListing(ctx context.Context, u *url.URL, query url.Values) ([]*Thing, error) {
+	it := api.newThingIterator(ctx, u, query, &ListingOptions{
+		Limit: maxListingLimit,
+		Max:   maxListingLimit,
+	})
+
+	var children []*Thing
+	for it.Next() {
+		children = append(children, it.Thing())
+	}
+	return children, it.Err()
+}
@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+	"github.com/joshbarrass/goreddit/fakereddit"
+)
+
+func TestStreamSubmissions(t *testing.T) {
+	s := fakereddit.NewServer("id", "secret")
+	defer s.Close()
+	// keep the rate limit from slowing down polling
+	s.RateLimitWindow = time.Second
+	s.AddUser("bot", "pw")
+	s.AddSubreddit("test")
+	api := login(t, s, "bot")
+	existing := s.AddPost("test", "bot", "existing", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// a capacity smaller than a page must not cause repeats
+	opts := &reddit.StreamOptions{
+		MinInterval:  time.Millisecond,
+		MaxInterval:  time.Millisecond,
+		SeenCapacity: 1,
+		OnError:      func(err error) { t.Error(err) },
+	}
+	posts := api.StreamSubmissions(ctx, opts, "test")
+
+	next := func() *reddit.PostResponse {
+		select {
+		case post := <-posts:
+			return post
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a post")
+			return nil
+		}
+	}
+	if post := next(); post.ID != existing.ID {
+		t.Fatalf("got %s, want the existing post %s", post.ID, existing.ID)
+	}
+	added := s.AddPost("test", "bot", "new", "")
+	if post := next(); post.ID != added.ID {
+		t.Fatalf("got %s, want the new post %s", post.ID, added.ID)
+	}
+
+	// several more polls find nothing new
+	select {
+	case post := <-posts:
+		t.Errorf("got %s again", post.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStreamValidation(t *testing.T) {
+	api := reddit.NewRedditAPI("id", "secret", "test", "bot", false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the stream reports the error and closes without polling
+	var errs []error
+	opts := &reddit.StreamOptions{OnError: func(err error) { errs = append(errs, err) }}
+	for range api.StreamSubmissions(ctx, opts) {
+		t.Error("got a post with no subreddits")
+	}
+	for range api.StreamComments(ctx, opts, "test", "") {
+		t.Error("got a comment with a blank subreddit")
+	}
+	if len(errs) != 2 {
+		t.Fatalf("got errors %v, want 2", errs)
+	}
+	for _, err := range errs {
+		var validationErr *reddit.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != "subreddits" {
+			t.Errorf("got %v, want a *ValidationError for subreddits", err)
+		}
+	}
+}
@@ -0,0 +1,672 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// submission kinds
+const (
+	SubmitKindSelf     = "self"
+	SubmitKindLink     = "link"
+	SubmitKindImage    = "image"
+	SubmitKindVideo    = "video"
+	SubmitKindVideoGIF = "videogif"
+)
+
+// limits on submissions
+const (
+	maxTitleLen          = 300
+	maxSelfTextLen       = 40000
+	maxFlairTextLen      = 64
+	minGalleryItems      = 2
+	maxGalleryItems      = 20
+	maxGalleryCaptionLen = 180
+	minPollOptions       = 2
+	maxPollOptions       = 6
+	maxPollOptionLen     = 120
+	minPollDuration      = 1
+	maxPollDuration      = 7
+)
+
+// eventTimeFormat is the format of event start and end times
+const eventTimeFormat = "2006-01-02T15:04:05"
+
+// SubmitOptions holds the optional settings for a submission. When no
+// options are given, SendReplies is true and everything else is off.
+type SubmitOptions struct {
+	// Ad marks the post as an advertisement
+	Ad      bool
+	NSFW    bool
+	Spoiler bool
+	// SendReplies sends replies to the post to the author's inbox
+	SendReplies bool
+
+	// FlairID is the ID of a flair template to apply, and
+	// FlairText overrides the template's text if it is editable
+	FlairID   string
+	FlairText string
+
+	// CollectionID adds the post to a collection
+	CollectionID string
+
+	// EventStart and EventEnd make the post an event. Both must be
+	// set, and EventTimezone is the timezone name to show them in,
+	// e.g. "Europe/London". If blank, the times' location is used,
+	// which must be UTC or loaded by name rather than being Local, as
+	// that is often a default rather than the event's timezone.
+	EventStart    time.Time
+	EventEnd      time.Time
+	EventTimezone string
+
+	// DraftID is the ID of a draft to submit, which is deleted
+	// once the post has been made
+	DraftID string
+}
+
+// defaultSubmitOptions are used when no options are given
+var defaultSubmitOptions = SubmitOptions{
+	SendReplies: true,
+}
+
+// validate checks the options, returning a *ValidationError
+func (opts *SubmitOptions) validate() error {
+	if len([]rune(opts.FlairText)) > maxFlairTextLen {
+		return &ValidationError{Field: "flair_text", Message: fmt.Sprintf("longer than %d characters", maxFlairTextLen)}
+	}
+	if opts.EventStart.IsZero() != opts.EventEnd.IsZero() {
+		return &ValidationError{Field: "event_end", Message: "events need both a start and an end"}
+	}
+	if !opts.EventStart.IsZero() && !opts.EventEnd.After(opts.EventStart) {
+		return &ValidationError{Field: "event_end", Message: "event ends before it starts"}
+	}
+	if !opts.EventStart.IsZero() && opts.timezone() == "" {
+		return &ValidationError{Field: "event_tz", Message: fmt.Sprintf("no timezone given for location %q", opts.EventStart.Location())}
+	}
+	return nil
+}
+
+// timezone returns the timezone of the event, or "" if it must be given
+// in EventTimezone
+func (opts *SubmitOptions) timezone() string {
+	if opts.EventTimezone != "" {
+		return opts.EventTimezone
+	}
+	name := opts.EventStart.Location().String()
+	switch name {
+	case "", "Local":
+		return ""
+	case "UTC":
+		return name
+	}
+	// fixed zones may have names that reddit won't recognise
+	if _, err := time.LoadLocation(name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// encode adds the options to the form data for a submission
+func (opts *SubmitOptions) encode(data url.Values) {
+	data.Set("ad", strconv.FormatBool(opts.Ad))
+	data.Set("nsfw", strconv.FormatBool(opts.NSFW))
+	data.Set("spoiler", strconv.FormatBool(opts.Spoiler))
+	data.Set("sendreplies", strconv.FormatBool(opts.SendReplies))
+	if opts.FlairID != "" {
+		data.Set("flair_id", opts.FlairID)
+	}
+	if opts.FlairText != "" {
+		data.Set("flair_text", opts.FlairText)
+	}
+	if opts.CollectionID != "" {
+		data.Set("collection_id", opts.CollectionID)
+	}
+	if !opts.EventStart.IsZero() {
+		data.Set("event_start", opts.EventStart.Format(eventTimeFormat))
+		data.Set("event_end", opts.EventEnd.Format(eventTimeFormat))
+		data.Set("event_tz", opts.timezone())
+	}
+	if opts.DraftID != "" {
+		data.Set("draft_id", opts.DraftID)
+	}
+}
+
+// submitOptions returns the options to use, falling back to the
+// defaults if opts is nil
+func submitOptions(opts *SubmitOptions) *SubmitOptions {
+	if opts == nil {
+		o := defaultSubmitOptions
+		return &o
+	}
+	return opts
+}
+
+// validateTitle checks the title of a submission
+func validateTitle(title string) error {
+	if strings.TrimSpace(title) == "" {
+		return &ValidationError{Field: "title", Message: "empty"}
+	}
+	if len([]rune(title)) > maxTitleLen {
+		return &ValidationError{Field: "title", Message: fmt.Sprintf("longer than %d characters", maxTitleLen)}
+	}
+	return nil
+}
+
+// MediaFile is a file to upload to reddit
+type MediaFile struct {
+	// Name is the filename. If MimeType is blank, it is guessed
+	// from the extension.
+	Name     string
+	MimeType string
+	Body     io.Reader
+}
+
+// MediaAsset is a file that has been uploaded to reddit
+type MediaAsset struct {
+	ID  string
+	URL string
+}
+
+// GalleryItem is an image in a gallery post. Caption and OutboundURL
+// are optional.
+type GalleryItem struct {
+	Image       *MediaFile
+	Caption     string
+	OutboundURL string
+}
+
+// submit validates a submission and sends it to /api/submit with the
+// options set
+func (api *RedditAPI) submit(ctx context.Context, data url.Values, opts *SubmitOptions) (*SubmitPostData, error) {
+	if err := validateTitle(data.Get("title")); err != nil {
+		return nil, err
+	}
+	opts = submitOptions(opts)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	opts.encode(data)
+
+	u := api.GetOauthURL(OauthEndpointSubmitPost)
+
+	// send request
+	resp, err := api.PostFormContext(ctx, u, data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeSubmitPostResponse(resp)
+}
+
+// decodeSubmitPostResponse decodes the response to a submission
+func decodeSubmitPostResponse(resp *http.Response) (*SubmitPostData, error) {
+	var response intermediateSubmitPostResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	if err := response.Error(); err != nil {
+		return nil, err
+	}
+	postData := response.GetData()
+	if postData.ID == "" && postData.WebsocketURL == "" {
+		return nil, errors.New("empty post ID")
+	}
+	return postData, nil
+}
+
+// RequestSubmitLinkPost submits a link post to a subreddit. Unless
+// resubmit is true, reddit will refuse links that have already been
+// submitted to the subreddit with ErrCodeAlreadySub. opts may be nil.
+func (api *RedditAPI) RequestSubmitLinkPost(subreddit, title, link string, resubmit bool, opts *SubmitOptions) (*SubmitPostData, error) {
+	return api.RequestSubmitLinkPostContext(context.Background(), subreddit, title, link, resubmit, opts)
+}
+
+// RequestSubmitLinkPostContext is like RequestSubmitLinkPost but with
+// a context
+func (api *RedditAPI) RequestSubmitLinkPostContext(ctx context.Context, subreddit, title, link string, resubmit bool, opts *SubmitOptions) (*SubmitPostData, error) {
+	if u, err := url.Parse(link); err != nil || (u.Scheme != HTTP && u.Scheme != HTTPS) || u.Host == "" {
+		return nil, &ValidationError{Field: "url", Message: "not an absolute http(s) URL"}
+	}
+
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"kind":     {SubmitKindLink},
+		"resubmit": {strconv.FormatBool(resubmit)},
+		"sr":       {subreddit},
+		"title":    {title},
+		"url":      {link},
+	}
+
+	// send request
+	return api.submit(ctx, data, opts)
+}
+
+// RequestSubmitImagePost uploads an image and submits it to a
+// subreddit. Reddit processes the image after submission, so the ID
+// may be blank, in which case the post can be found through the
+// WebsocketURL or UserSubmittedPage. opts may be nil.
+func (api *RedditAPI) RequestSubmitImagePost(subreddit, title string, image *MediaFile, opts *SubmitOptions) (*SubmitPostData, error) {
+	return api.RequestSubmitImagePostContext(context.Background(), subreddit, title, image, opts)
+}
+
+// RequestSubmitImagePostContext is like RequestSubmitImagePost but
+// with a context
+func (api *RedditAPI) RequestSubmitImagePostContext(ctx context.Context, subreddit, title string, image *MediaFile, opts *SubmitOptions) (*SubmitPostData, error) {
+	// check before uploading anything
+	if err := validateTitle(title); err != nil {
+		return nil, err
+	}
+	if err := submitOptions(opts).validate(); err != nil {
+		return nil, err
+	}
+
+	asset, err := api.RequestUploadMediaContext(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"kind":     {SubmitKindImage},
+		"sr":       {subreddit},
+		"title":    {title},
+		"url":      {asset.URL},
+	}
+
+	// send request
+	return api.submit(ctx, data, opts)
+}
+
+// RequestSubmitVideoPost uploads a video and its poster (thumbnail)
+// image and submits them to a subreddit. If gif is true, the video is
+// posted without sound as a videogif. As with images, the ID may be
+// blank while reddit processes the video. opts may be nil.
+func (api *RedditAPI) RequestSubmitVideoPost(subreddit, title string, video, poster *MediaFile, gif bool, opts *SubmitOptions) (*SubmitPostData, error) {
+	return api.RequestSubmitVideoPostContext(context.Background(), subreddit, title, video, poster, gif, opts)
+}
+
+// RequestSubmitVideoPostContext is like RequestSubmitVideoPost but
+// with a context
+func (api *RedditAPI) RequestSubmitVideoPostContext(ctx context.Context, subreddit, title string, video, poster *MediaFile, gif bool, opts *SubmitOptions) (*SubmitPostData, error) {
+	// check before uploading anything
+	if err := validateTitle(title); err != nil {
+		return nil, err
+	}
+	if err := submitOptions(opts).validate(); err != nil {
+		return nil, err
+	}
+	if poster == nil {
+		return nil, &ValidationError{Field: "video_poster_url", Message: "video posts need a poster image"}
+	}
+	videoAsset, err := api.RequestUploadMediaContext(ctx, video)
+	if err != nil {
+		return nil, err
+	}
+	posterAsset, err := api.RequestUploadMediaContext(ctx, poster)
+	if err != nil {
+		return nil, err
+	}
+
+	kind := SubmitKindVideo
+	if gif {
+		kind = SubmitKindVideoGIF
+	}
+
+	// construct post data
+	data := url.Values{
+		"api_type":         {"json"},
+		"kind":             {kind},
+		"sr":               {subreddit},
+		"title":            {title},
+		"url":              {videoAsset.URL},
+		"video_poster_url": {posterAsset.URL},
+	}
+
+	// send request
+	return api.submit(ctx, data, opts)
+}
+
+// galleryPostRequest is the JSON body for a gallery submission
+type galleryPostRequest struct {
+	APIType       string            `json:"api_type"`
+	Subreddit     string            `json:"sr"`
+	Title         string            `json:"title"`
+	Items         []galleryPostItem `json:"items"`
+	ShowErrorList bool              `json:"show_error_list"`
+	NSFW          bool              `json:"nsfw"`
+	Spoiler       bool              `json:"spoiler"`
+	SendReplies   bool              `json:"sendreplies"`
+	FlairID       string            `json:"flair_id,omitempty"`
+	FlairText     string            `json:"flair_text,omitempty"`
+	CollectionID  string            `json:"collection_id,omitempty"`
+	EventStart    string            `json:"event_start,omitempty"`
+	EventEnd      string            `json:"event_end,omitempty"`
+	EventTimezone string            `json:"event_tz,omitempty"`
+	DraftID       string            `json:"draft_id,omitempty"`
+}
+
+type galleryPostItem struct {
+	MediaID     string `json:"media_id"`
+	Caption     string `json:"caption"`
+	OutboundURL string `json:"outbound_url"`
+}
+
+// RequestSubmitGalleryPost uploads between 2 and 20 images and
+// submits them to a subreddit as a gallery. opts may be nil, and Ad
+// is not supported for galleries.
+func (api *RedditAPI) RequestSubmitGalleryPost(subreddit, title string, items []GalleryItem, opts *SubmitOptions) (*SubmitPostData, error) {
+	return api.RequestSubmitGalleryPostContext(context.Background(), subreddit, title, items, opts)
+}
+
+// RequestSubmitGalleryPostContext is like RequestSubmitGalleryPost but
+// with a context
+func (api *RedditAPI) RequestSubmitGalleryPostContext(ctx context.Context, subreddit, title string, items []GalleryItem, opts *SubmitOptions) (*SubmitPostData, error) {
+	// check everything before uploading anything
+	if err := validateTitle(title); err != nil {
+		return nil, err
+	}
+	opts = submitOptions(opts)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Ad {
+		return nil, &ValidationError{Field: "ad", Message: "galleries can't be ads"}
+	}
+	if len(items) < minGalleryItems || len(items) > maxGalleryItems {
+		return nil, &ValidationError{Field: "items", Message: fmt.Sprintf("galleries must have between %d and %d images", minGalleryItems, maxGalleryItems)}
+	}
+	for i, item := range items {
+		if item.Image == nil {
+			return nil, &ValidationError{Field: "items", Message: fmt.Sprintf("item %d has no image", i)}
+		}
+		if len([]rune(item.Caption)) > maxGalleryCaptionLen {
+			return nil, &ValidationError{Field: "items", Message: fmt.Sprintf("item %d caption is longer than %d characters", i, maxGalleryCaptionLen)}
+		}
+	}
+
+	request := galleryPostRequest{
+		APIType:       "json",
+		Subreddit:     subreddit,
+		Title:         title,
+		ShowErrorList: true,
+		NSFW:          opts.NSFW,
+		Spoiler:       opts.Spoiler,
+		SendReplies:   opts.SendReplies,
+		FlairID:       opts.FlairID,
+		FlairText:     opts.FlairText,
+		CollectionID:  opts.CollectionID,
+		DraftID:       opts.DraftID,
+	}
+	if !opts.EventStart.IsZero() {
+		request.EventStart = opts.EventStart.Format(eventTimeFormat)
+		request.EventEnd = opts.EventEnd.Format(eventTimeFormat)
+		request.EventTimezone = opts.timezone()
+	}
+
+	// upload the images
+	for _, item := range items {
+		asset, err := api.RequestUploadMediaContext(ctx, item.Image)
+		if err != nil {
+			return nil, err
+		}
+		request.Items = append(request.Items, galleryPostItem{
+			MediaID:     asset.ID,
+			Caption:     item.Caption,
+			OutboundURL: item.OutboundURL,
+		})
+	}
+
+	// send request
+	u := api.GetOauthURL(OauthEndpointSubmitGalleryPost)
+	resp, err := api.postJSON(ctx, u, request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeSubmitPostResponse(resp)
+}
+
+// pollPostRequest is the JSON body for a poll submission
+type pollPostRequest struct {
+	APIType       string   `json:"api_type"`
+	Subreddit     string   `json:"sr"`
+	Title         string   `json:"title"`
+	Text          string   `json:"text"`
+	Options       []string `json:"options"`
+	Duration      int      `json:"duration"`
+	NSFW          bool     `json:"nsfw"`
+	Spoiler       bool     `json:"spoiler"`
+	SendReplies   bool     `json:"sendreplies"`
+	FlairID       string   `json:"flair_id,omitempty"`
+	FlairText     string   `json:"flair_text,omitempty"`
+	CollectionID  string   `json:"collection_id,omitempty"`
+	EventStart    string   `json:"event_start,omitempty"`
+	EventEnd      string   `json:"event_end,omitempty"`
+	EventTimezone string   `json:"event_tz,omitempty"`
+	DraftID       string   `json:"draft_id,omitempty"`
+}
+
+// RequestSubmitPollPost submits a poll to a subreddit. There must be
+// between 2 and 6 options, and duration is the number of days voting
+// is open for, from 1 to 7. The text may be empty. opts may be nil,
+// and Ad is not supported for polls.
+func (api *RedditAPI) RequestSubmitPollPost(subreddit, title, text string, options []string, duration int, opts *SubmitOptions) (*SubmitPostData, error) {
+	return api.RequestSubmitPollPostContext(context.Background(), subreddit, title, text, options, duration, opts)
+}
+
+// RequestSubmitPollPostContext is like RequestSubmitPollPost but with
+// a context
+func (api *RedditAPI) RequestSubmitPollPostContext(ctx context.Context, subreddit, title, text string, options []string, duration int, opts *SubmitOptions) (*SubmitPostData, error) {
+	if err := validateTitle(title); err != nil {
+		return nil, err
+	}
+	opts = submitOptions(opts)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if opts.Ad {
+		return nil, &ValidationError{Field: "ad", Message: "polls can't be ads"}
+	}
+	if len([]rune(text)) > maxSelfTextLen {
+		return nil, &ValidationError{Field: "text", Message: fmt.Sprintf("longer than %d characters", maxSelfTextLen)}
+	}
+	if len(options) < minPollOptions || len(options) > maxPollOptions {
+		return nil, &ValidationError{Field: "options", Message: fmt.Sprintf("polls must have between %d and %d options", minPollOptions, maxPollOptions)}
+	}
+	for i, option := range options {
+		if strings.TrimSpace(option) == "" {
+			return nil, &ValidationError{Field: "options", Message: fmt.Sprintf("option %d is empty", i)}
+		}
+		if len([]rune(option)) > maxPollOptionLen {
+			return nil, &ValidationError{Field: "options", Message: fmt.Sprintf("option %d is longer than %d characters", i, maxPollOptionLen)}
+		}
+	}
+	if duration < minPollDuration || duration > maxPollDuration {
+		return nil, &ValidationError{Field: "duration", Message: fmt.Sprintf("must be between %d and %d days", minPollDuration, maxPollDuration)}
+	}
+
+	request := pollPostRequest{
+		APIType:      "json",
+		Subreddit:    subreddit,
+		Title:        title,
+		Text:         text,
+		Options:      options,
+		Duration:     duration,
+		NSFW:         opts.NSFW,
+		Spoiler:      opts.Spoiler,
+		SendReplies:  opts.SendReplies,
+		FlairID:      opts.FlairID,
+		FlairText:    opts.FlairText,
+		CollectionID: opts.CollectionID,
+		DraftID:      opts.DraftID,
+	}
+	if !opts.EventStart.IsZero() {
+		request.EventStart = opts.EventStart.Format(eventTimeFormat)
+		request.EventEnd = opts.EventEnd.Format(eventTimeFormat)
+		request.EventTimezone = opts.timezone()
+	}
+
+	// send request
+	u := api.GetOauthURL(OauthEndpointSubmitPollPost)
+	resp, err := api.postJSON(ctx, u, request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeSubmitPostResponse(resp)
+}
+
+// RequestUploadMedia uploads a file for use in a post. Reddit first
+// leases an upload slot, then the file is uploaded to its media
+// storage.
+func (api *RedditAPI) RequestUploadMedia(file *MediaFile) (*MediaAsset, error) {
+	return api.RequestUploadMediaContext(context.Background(), file)
+}
+
+// RequestUploadMediaContext is like RequestUploadMedia but with a
+// context
+func (api *RedditAPI) RequestUploadMediaContext(ctx context.Context, file *MediaFile) (*MediaAsset, error) {
+	if file == nil || file.Body == nil {
+		return nil, errors.New("no media to upload")
+	}
+	mimeType := file.MimeType
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(path.Ext(file.Name))
+	}
+	if mimeType == "" {
+		return nil, errors.New(fmt.Sprintf("unknown media type for %s", file.Name))
+	}
+
+	u := api.GetOauthURL(OauthEndpointMediaAsset)
+
+	// construct post data
+	data := url.Values{
+		"filepath": {path.Base(file.Name)},
+		"mimetype": {mimeType},
+	}
+
+	// request an upload lease
+	resp, err := api.postForm(ctx, u, data, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response mediaAssetResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	if err := response.Error(); err != nil {
+		return nil, err
+	}
+	if response.Asset.AssetID == "" || response.Args.Action == "" {
+		return nil, errors.New("no upload lease returned")
+	}
+
+	// upload the file
+	mediaURL, err := api.uploadLeased(ctx, &response.Args, file, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MediaAsset{
+		ID:  response.Asset.AssetID,
+		URL: mediaURL,
+	}, nil
+}
+
+// s3PostResponse is the XML returned by a successful upload
+type s3PostResponse struct {
+	Location string `xml:"Location"`
+}
+
+// uploadLeased uploads a file to the storage given in an upload lease,
+// returning the URL of the uploaded file
+func (api *RedditAPI) uploadLeased(ctx context.Context, lease *mediaUploadLease, file *MediaFile, mimeType string) (string, error) {
+	// the action is usually protocol-relative
+	action, err := url.Parse(lease.Action)
+	if err != nil {
+		return "", err
+	}
+	action = api.oauthBaseURL().ResolveReference(action)
+
+	// construct the form, with the file last
+	var (
+		body bytes.Buffer
+		key  string
+	)
+	form := multipart.NewWriter(&body)
+	for _, field := range lease.Fields {
+		if field.Name == "key" {
+			key = field.Value
+		}
+		if err := form.WriteField(field.Name, field.Value); err != nil {
+			return "", err
+		}
+	}
+	part, err := form.CreatePart(map[string][]string{
+		"Content-Disposition": {fmt.Sprintf(`form-data; name="file"; filename=%q`, path.Base(file.Name))},
+		"Content-Type":        {mimeType},
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, file.Body); err != nil {
+		return "", err
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	// the storage is not part of the API, so don't send any
+	// credentials or count it against the rate limit
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, action.String(), &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("User-Agent", api.UserAgent)
+
+	// send request
+	resp, err := api.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// check for 2xx
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &HTTPStatusError{StatusCode: resp.StatusCode, Message: "media upload failed"}
+	}
+
+	// prefer the location reported by the storage
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var s3Response s3PostResponse
+	if xml.Unmarshal(bodyBytes, &s3Response) == nil && s3Response.Location != "" {
+		if location, err := url.PathUnescape(s3Response.Location); err == nil {
+			return location, nil
+		}
+	}
+	return strings.TrimSuffix(action.String(), "/") + "/" + key, nil
+}
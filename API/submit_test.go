@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestSubmitEvent(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// the times' location is used when no timezone is given
+	start := time.Date(2030, 6, 1, 10, 0, 0, 0, london)
+	opts := &reddit.SubmitOptions{EventStart: start, EventEnd: start.Add(time.Hour)}
+	d, err := api.RequestSubmitTextPostWithOptions("test", "event", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, _ := s.Post(d.ID); !post.EventStart.Equal(start) {
+		t.Errorf("got start %s, want %s", post.EventStart, start)
+	}
+
+	// EventTimezone overrides it
+	start = time.Date(2030, 6, 1, 10, 0, 0, 0, time.UTC)
+	opts = &reddit.SubmitOptions{EventStart: start, EventEnd: start.Add(time.Hour), EventTimezone: "Europe/London"}
+	d, err = api.RequestSubmitTextPostWithOptions("test", "event", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, _ := s.Post(d.ID); !post.EventStart.Equal(time.Date(2030, 6, 1, 10, 0, 0, 0, london)) {
+		t.Errorf("got start %s, want 10:00 in London", post.EventStart)
+	}
+}
+
+func TestSubmitEventTimezone(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	// these locations don't say which timezone the event is in
+	for _, loc := range []*time.Location{time.Local, time.FixedZone("", 3600), time.FixedZone("UTC+1", 3600)} {
+		start := time.Date(2030, 6, 1, 10, 0, 0, 0, loc)
+		opts := &reddit.SubmitOptions{EventStart: start, EventEnd: start.Add(time.Hour)}
+		_, err := api.RequestSubmitTextPostWithOptions("test", "event", "", opts)
+		var validationErr *reddit.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != "event_tz" {
+			t.Errorf("got %v for location %q, want a *ValidationError for event_tz", err, loc)
+		}
+	}
+
+	start := time.Date(2030, 6, 1, 10, 0, 0, 0, time.UTC)
+	opts := &reddit.SubmitOptions{EventStart: start, EventEnd: start.Add(time.Hour)}
+	d, err := api.RequestSubmitTextPostWithOptions("test", "event", "", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, _ := s.Post(d.ID); !post.EventStart.Equal(start) || post.EventStart.Location().String() != "UTC" {
+		t.Errorf("got event start %v", post.EventStart)
+	}
+}
+
+// mediaFile returns a file whose contents are its name
+func mediaFile(name string) *reddit.MediaFile {
+	return &reddit.MediaFile{Name: name, Body: strings.NewReader(name)}
+}
+
+func TestSubmitLinkPost(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	d, err := api.RequestSubmitLinkPost("test", "link", "https://example.com", false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, _ := s.Post(d.ID); post.URL != "https://example.com" || post.IsSelf {
+		t.Errorf("got %+v", post)
+	}
+
+	// resubmitting must be asked for
+	_, err = api.RequestSubmitLinkPost("test", "link", "https://example.com", false, nil)
+	if !errors.Is(err, reddit.ErrCodeAlreadySub) {
+		t.Errorf("got %v, want ALREADY_SUB", err)
+	}
+	if _, err := api.RequestSubmitLinkPost("test", "link", "https://example.com", true, nil); err != nil {
+		t.Error(err)
+	}
+
+	var validationErr *reddit.ValidationError
+	_, err = api.RequestSubmitLinkPost("test", "link", "not a url", false, nil)
+	if !errors.As(err, &validationErr) {
+		t.Errorf("got %v, want a *ValidationError", err)
+	}
+}
+
+func TestSubmitMediaPosts(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	d, err := api.RequestSubmitImagePost("test", "image", mediaFile("a.png"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, _ := s.Post(d.ID); post.URL == "" {
+		t.Errorf("got %+v", post)
+	}
+
+	d, err = api.RequestSubmitVideoPost("test", "video", mediaFile("v.mp4"), mediaFile("poster.jpg"), false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post, _ := s.Post(d.ID); !post.IsVideo || post.PosterURL == "" {
+		t.Errorf("got %+v", post)
+	}
+
+	items := []reddit.GalleryItem{
+		{Image: mediaFile("1.png"), Caption: "one"},
+		{Image: mediaFile("2.gif"), OutboundURL: "https://example.com"},
+	}
+	d, err = api.RequestSubmitGalleryPost("test", "gallery", items, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, _ := s.Post(d.ID)
+	if len(post.Gallery) != 2 || post.Gallery[0].Caption != "one" {
+		t.Fatalf("got gallery %+v", post.Gallery)
+	}
+	if asset, _ := s.MediaAsset(post.Gallery[0].MediaID); string(asset.Data) != "1.png" {
+		t.Errorf("got uploaded data %q", asset.Data)
+	}
+
+	// galleries need at least two items
+	items = []reddit.GalleryItem{{Image: mediaFile("1.png")}}
+	var validationErr *reddit.ValidationError
+	if _, err := api.RequestSubmitGalleryPost("test", "gallery", items, nil); !errors.As(err, &validationErr) {
+		t.Errorf("got %v, want a *ValidationError", err)
+	}
+	items = append(items, reddit.GalleryItem{Image: mediaFile("2.png")})
+	if _, err := api.RequestSubmitGalleryPost("test", "gallery", items, &reddit.SubmitOptions{Ad: true}); !errors.As(err, &validationErr) || validationErr.Field != "ad" {
+		t.Errorf("got %v for an ad, want a *ValidationError for ad", err)
+	}
+}
+
+func TestSubmitPollPost(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+
+	d, err := api.RequestSubmitPollPost("test", "vote", "pick one", []string{"a", "b", "c"}, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := s.Post(d.ID)
+	s.VotePoll(d.ID, "other", stored.Poll.Options[1].ID)
+	u, _ := url.Parse(d.URL)
+
+	// counts are hidden until the user votes
+	post, err := api.RequestPostJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poll := post.PollData
+	if poll == nil || len(poll.Options) != 3 || poll.Options[1].VoteCount != nil || poll.UserSelection != "" {
+		t.Fatalf("got %+v before voting", poll)
+	}
+	if days := time.Until(poll.VotingEnd()).Hours() / 24; days < 2.9 || days > 3 {
+		t.Errorf("voting ends in %.1f days, want 3", days)
+	}
+
+	s.VotePoll(d.ID, "bot", stored.Poll.Options[1].ID)
+	post, err = api.RequestPostJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poll = post.PollData
+	option, ok := poll.Option(poll.UserSelection)
+	if !ok || option.Text != "b" || option.VoteCount == nil || *option.VoteCount != 2 || poll.TotalVoteCount != 2 {
+		t.Errorf("got %+v after voting", poll)
+	}
+
+	// polls need two to six options and a duration of one to seven
+	// days
+	var validationErr *reddit.ValidationError
+	if _, err := api.RequestSubmitPollPost("test", "vote", "", []string{"a"}, 3, nil); !errors.As(err, &validationErr) {
+		t.Errorf("got %v with one option, want a *ValidationError", err)
+	}
+	if _, err := api.RequestSubmitPollPost("test", "vote", "", []string{"a", "b"}, 8, nil); !errors.As(err, &validationErr) {
+		t.Errorf("got %v for eight days, want a *ValidationError", err)
+	}
+	if _, err := api.RequestSubmitPollPost("test", "vote", "", []string{"a", "b"}, 3, &reddit.SubmitOptions{Ad: true}); !errors.As(err, &validationErr) || validationErr.Field != "ad" {
+		t.Errorf("got %v for an ad, want a *ValidationError for ad", err)
+	}
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"encoding/json"
+)
+
+// kinds of thing
+const (
+	KindComment    = "t1"
+	KindAccount    = "t2"
+	KindPost       = "t3"
+	KindMessage    = "t4"
+	KindSubreddit  = "t5"
+	KindAward      = "t6"
+	KindMore       = "more"
+	KindListing    = "Listing"
+	KindLiveUpdate = "LiveUpdate"
+	KindModAction  = "modaction"
+	KindUserList   = "UserList"
+	// KindRelationship is given to the entries of a UserList, which
+	// have no kind of their own
+	KindRelationship = "relationship"
+)
+
+// Thing is an object returned by reddit, decoded according to its
+// kind. Data holds a *CommentResponse, *AccountResponse,
+// *PostResponse, *MessageResponse, *SubredditResponse, *MoreChildren,
+// *LiveUpdateResponse, *ModAction, *Relationship or *Listing. A
+// UserList is decoded as a *Listing of relationships. Kinds that are
+// not understood are left as a json.RawMessage.
+type Thing struct {
+	Kind string
+	Data interface{}
+}
+
+// UnmarshalJSON decodes the thing's data into the type for its kind
+func (t *Thing) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Kind string          `json:"kind"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	t.Kind = raw.Kind
+
+	var v interface{}
+	switch raw.Kind {
+	case KindComment:
+		v = &CommentResponse{}
+	case KindAccount:
+		v = &AccountResponse{}
+	case KindPost:
+		v = &PostResponse{}
+	case KindMessage:
+		v = &MessageResponse{}
+	case KindSubreddit:
+		v = &SubredditResponse{}
+	case KindMore:
+		v = &MoreChildren{}
+	case KindLiveUpdate:
+		v = &LiveUpdateResponse{}
+	case KindModAction:
+		v = &ModAction{}
+	case KindListing:
+		v = &Listing{}
+	case KindUserList:
+		return t.decodeUserList(raw.Data)
+	default:
+		t.Data = raw.Data
+		return nil
+	}
+	if err := json.Unmarshal(raw.Data, v); err != nil {
+		return err
+	}
+
+	// comments carry their replies as a nested listing
+	if comment, ok := v.(*CommentResponse); ok {
+		if err := comment.DecodeReplies(); err != nil {
+			return err
+		}
+	}
+
+	t.Data = v
+	return nil
+}
+
+// Fullname returns the fullname of the thing, if it has one
+func (t *Thing) Fullname() Fullname {
+	switch data := t.Data.(type) {
+	case *CommentResponse:
+		return data.Name
+	case *AccountResponse:
+		return Fullname(KindAccount + "_" + data.ID)
+	case *PostResponse:
+		return data.Name
+	case *MessageResponse:
+		return data.Name
+	case *SubredditResponse:
+		return data.Name
+	case *MoreChildren:
+		return data.Name
+	case *LiveUpdateResponse:
+		return Fullname(data.Name)
+	case *ModAction:
+		return Fullname(data.ID)
+	case *Relationship:
+		return Fullname(data.RelID)
+	}
+	return ""
+}
+
+// Comment returns the thing as a comment
+func (t *Thing) Comment() (*CommentResponse, bool) {
+	v, ok := t.Data.(*CommentResponse)
+	return v, ok
+}
+
+// Account returns the thing as an account
+func (t *Thing) Account() (*AccountResponse, bool) {
+	v, ok := t.Data.(*AccountResponse)
+	return v, ok
+}
+
+// Post returns the thing as a post
+func (t *Thing) Post() (*PostResponse, bool) {
+	v, ok := t.Data.(*PostResponse)
+	return v, ok
+}
+
+// Message returns the thing as a message
+func (t *Thing) Message() (*MessageResponse, bool) {
+	v, ok := t.Data.(*MessageResponse)
+	return v, ok
+}
+
+// Subreddit returns the thing as a subreddit
+func (t *Thing) Subreddit() (*SubredditResponse, bool) {
+	v, ok := t.Data.(*SubredditResponse)
+	return v, ok
+}
+
+// More returns the thing as a "more" stub
+func (t *Thing) More() (*MoreChildren, bool) {
+	v, ok := t.Data.(*MoreChildren)
+	return v, ok
+}
+
+// LiveUpdate returns the thing as a live thread update
+func (t *Thing) LiveUpdate() (*LiveUpdateResponse, bool) {
+	v, ok := t.Data.(*LiveUpdateResponse)
+	return v, ok
+}
+
+// ModAction returns the thing as a moderation log entry
+func (t *Thing) ModAction() (*ModAction, bool) {
+	v, ok := t.Data.(*ModAction)
+	return v, ok
+}
+
+// Relationship returns the thing as an entry in a UserList
+func (t *Thing) Relationship() (*Relationship, bool) {
+	v, ok := t.Data.(*Relationship)
+	return v, ok
+}
+
+// Listing returns the thing as a listing
+func (t *Thing) Listing() (*Listing, bool) {
+	v, ok := t.Data.(*Listing)
+	return v, ok
+}
+
+// decodeUserList decodes a UserList into a listing, wrapping each
+// relationship in a thing so that it can be iterated over like any
+// other listing
+func (t *Thing) decodeUserList(data []byte) error {
+	var list struct {
+		After    Fullname       `json:"after"`
+		Before   Fullname       `json:"before"`
+		Children []Relationship `json:"children"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	listing := &Listing{
+		After:  list.After,
+		Before: list.Before,
+		Dist:   len(list.Children),
+	}
+	for i := range list.Children {
+		listing.Children = append(listing.Children, Thing{
+			Kind: KindRelationship,
+			Data: &list.Children[i],
+		})
+	}
+	t.Data = listing
+	return nil
+}
+
+// Listing is a page of things. After and Before are the fullnames to
+// pass to get the next or previous page, and Dist is the number of
+// things in the page.
+type Listing struct {
+	After    Fullname `json:"after"`
+	Before   Fullname `json:"before"`
+	Dist     int      `json:"dist"`
+	Children []Thing  `json:"children"`
+}
+
+// AccountResponse is a user account (t2)
+type AccountResponse struct {
+	Name             string    `json:"name"`
+	ID               string    `json:"id"`
+	CommentKarma     int       `json:"comment_karma"`
+	LinkKarma        int       `json:"link_karma"`
+	Created          FloatTime `json:"created"`
+	CreatedUTC       FloatTime `json:"created_utc"`
+	HasVerifiedEmail bool      `json:"has_verified_email"`
+	HasGold          bool      `json:"is_gold"`
+	IsMod            bool      `json:"is_mod"`
+	IsEmployee       bool      `json:"is_employee"`
+	IsSuspended      bool      `json:"is_suspended"`
+	IconURL          string    `json:"icon_img"`
+}
+
+// MessageResponse is a private message (t4)
+type MessageResponse struct {
+	Name             Fullname  `json:"name"`
+	ID               string    `json:"id"`
+	Author           string    `json:"author"`
+	Dest             string    `json:"dest"`
+	Subject          string    `json:"subject"`
+	Body             string    `json:"body"`
+	Subreddit        string    `json:"subreddit"`
+	ParentID         Fullname  `json:"parent_id"`
+	FirstMessageName Fullname  `json:"first_message_name"`
+	Context          string    `json:"context"`
+	New              bool      `json:"new"`
+	WasComment       bool      `json:"was_comment"`
+	Distinguished    string    `json:"distinguished"`
+	CreatedUTC       FloatTime `json:"created_utc"`
+}
+
+// SubredditResponse is a subreddit (t5)
+type SubredditResponse struct {
+	Name              Fullname  `json:"name"`
+	ID                string    `json:"id"`
+	DisplayName       string    `json:"display_name"`
+	Title             string    `json:"title"`
+	URL               string    `json:"url"`
+	PublicDescription string    `json:"public_description"`
+	Description       string    `json:"description"`
+	Subscribers       int64     `json:"subscribers"`
+	SubredditType     string    `json:"subreddit_type"`
+	NSFW              bool      `json:"over18"`
+	Quarantined       bool      `json:"quarantine"`
+	IsModerator       bool      `json:"user_is_moderator"`
+	IsSubscriber      bool      `json:"user_is_subscriber"`
+	CreatedUTC        FloatTime `json:"created_utc"`
+}
+
+// LiveUpdateResponse is an update in a live thread
+type LiveUpdateResponse struct {
+	Name       string    `json:"name"`
+	ID         string    `json:"id"`
+	Author     string    `json:"author"`
+	Body       string    `json:"body"`
+	Stricken   bool      `json:"stricken"`
+	CreatedUTC FloatTime `json:"created_utc"`
+}
+
+// ModAction is an entry in a subreddit's moderation log. Its ID, of
+// the form "ModAction_<uuid>", stands in for a fullname when
+// paginating.
+type ModAction struct {
+	ID              string    `json:"id"`
+	Action          string    `json:"action"`
+	Moderator       string    `json:"mod"`
+	ModeratorID     string    `json:"mod_id36"`
+	Subreddit       string    `json:"subreddit"`
+	TargetFullname  Fullname  `json:"target_fullname"`
+	TargetAuthor    string    `json:"target_author"`
+	TargetTitle     string    `json:"target_title"`
+	TargetPermalink string    `json:"target_permalink"`
+	TargetBody      string    `json:"target_body"`
+	Details         string    `json:"details"`
+	Description     string    `json:"description"`
+	CreatedUTC      FloatTime `json:"created_utc"`
+}
+
+// Relationship is a user's entry in one of a subreddit's lists of
+// banned, muted or approved users or moderators
+type Relationship struct {
+	Name  string    `json:"name"`
+	ID    Fullname  `json:"id"` // the user's fullname
+	RelID string    `json:"rel_id"`
+	Date  FloatTime `json:"date"`
+	Note  string    `json:"note"`
+	// DaysLeft is the number of days left of a temporary ban, or nil
+	// if it is permanent
+	DaysLeft *int `json:"days_left"`
+	// ModPermissions lists a moderator's permissions
+	ModPermissions []string `json:"mod_permissions"`
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestThingDecoding(t *testing.T) {
+	data := `{"kind": "Listing", "data": {"after": "t3_b", "dist": 4, "children": [
+		{"kind": "t3", "data": {"name": "t3_a", "title": "post"}},
+		{"kind": "t1", "data": {"name": "t1_c", "body": "comment", "replies": {"kind": "Listing", "data": {"children": [
+			{"kind": "t1", "data": {"name": "t1_d", "parent_id": "t1_c", "body": "reply", "replies": ""}}
+		]}}}},
+		{"kind": "more", "data": {"name": "t1__", "children": ["e", "f"]}},
+		{"kind": "t9", "data": {"name": "t9_x"}}
+	]}}`
+	var thing Thing
+	if err := json.Unmarshal([]byte(data), &thing); err != nil {
+		t.Fatal(err)
+	}
+	listing, ok := thing.Listing()
+	if !ok || listing.After != "t3_b" || len(listing.Children) != 4 {
+		t.Fatalf("got %+v", thing.Data)
+	}
+
+	if post, ok := listing.Children[0].Post(); !ok || post.Title != "post" || listing.Children[0].Fullname() != "t3_a" {
+		t.Errorf("got %+v for the post", listing.Children[0].Data)
+	}
+	comment, ok := listing.Children[1].Comment()
+	if !ok || comment.Body != "comment" {
+		t.Fatalf("got %+v for the comment", listing.Children[1].Data)
+	}
+	if len(comment.Replies) != 1 || comment.Replies[0].Body != "reply" {
+		t.Errorf("got replies %+v", comment.Replies)
+	}
+	if more, ok := listing.Children[2].More(); !ok || len(more.Children) != 2 {
+		t.Errorf("got %+v for the stub", listing.Children[2].Data)
+	}
+
+	// unknown kinds are kept raw
+	if _, ok := listing.Children[3].Data.(json.RawMessage); !ok || listing.Children[3].Fullname() != "" {
+		t.Errorf("got %T for an unknown kind", listing.Children[3].Data)
+	}
+}
+
+func TestAccountDecoding(t *testing.T) {
+	data := `{"kind": "t2", "data": {"name": "bot", "id": "abc", "link_karma": 5}}`
+	var thing Thing
+	if err := json.Unmarshal([]byte(data), &thing); err != nil {
+		t.Fatal(err)
+	}
+	account, ok := thing.Account()
+	if !ok || account.Name != "bot" || account.LinkKarma != 5 {
+		t.Fatalf("got %+v", thing.Data)
+	}
+
+	// an account's name is its username, not its fullname
+	if got := thing.Fullname(); got != "t2_abc" {
+		t.Errorf("got fullname %q", got)
+	}
+}
+
+func TestUserListDecoding(t *testing.T) {
+	data := `{"kind": "UserList", "data": {"children": [
+		{"name": "bot", "id": "t2_1", "rel_id": "rb_1", "date": 1600000000.0, "note": "spam", "days_left": 3}
+	]}}`
+	var thing Thing
+	if err := json.Unmarshal([]byte(data), &thing); err != nil {
+		t.Fatal(err)
+	}
+	listing, ok := thing.Listing()
+	if !ok || len(listing.Children) != 1 {
+		t.Fatalf("got %+v", thing.Data)
+	}
+	rel, ok := listing.Children[0].Relationship()
+	if !ok || rel.Name != "bot" || rel.DaysLeft == nil || *rel.DaysLeft != 3 || listing.Children[0].Fullname() != "rb_1" {
+		t.Errorf("got %+v", listing.Children[0].Data)
+	}
+}
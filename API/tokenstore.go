@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TokenStore persists tokens so that they can be reused between runs
+// or shared between processes. Load should return a nil token and no
+// error if nothing has been stored yet.
+type TokenStore interface {
+	Load() (*Token, error)
+	Save(token *Token) error
+}
+
+// FileTokenStore stores a token as JSON in a file. The file is only
+// readable by the owner and is replaced atomically, so several
+// processes can safely share it.
+type FileTokenStore struct {
+	Path string
+}
+
+// NewFileTokenStore creates a new FileTokenStore using the given path
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{
+		Path: path,
+	}
+}
+
+// Load reads the token from the file
+func (s *FileTokenStore) Load() (*Token, error) {
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Save writes the token to a temporary file in the same directory and
+// then renames it over the old file
+func (s *FileTokenStore) Save(token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	// TempFile creates the file with 0600 permissions
+	dir, name := filepath.Split(s.Path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := ioutil.TempFile(dir, name+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, s.Path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// MemoryTokenStore stores a token in memory. It is mostly useful for
+// tests.
+type MemoryTokenStore struct {
+	mu    sync.Mutex
+	token *Token
+}
+
+// Load returns a copy of the stored token
+func (s *MemoryTokenStore) Load() (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, nil
+	}
+	token := *s.token
+	return &token, nil
+}
+
+// Save stores a copy of the token
+func (s *MemoryTokenStore) Save(token *Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := *token
+	s.token = &t
+	return nil
+}
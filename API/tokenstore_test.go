@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokenstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := NewFileTokenStore(filepath.Join(dir, "token.json"))
+
+	// nothing stored yet
+	if token, err := store.Load(); token != nil || err != nil {
+		t.Fatalf("got %v, %v from an empty store", token, err)
+	}
+
+	want := &Token{
+		Token:        "a1",
+		ExpiresIn:    TokenExpiresIn(time.Hour),
+		RefreshToken: "r1",
+		GrantType:    GrantTypeInstalledClient,
+		DeviceID:     "device",
+		Username:     "bot",
+		Expiry:       time.Now().Add(time.Hour).Round(0),
+	}
+	if err := store.Save(want); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("got permissions %v", info.Mode().Perm())
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Token != want.Token || got.ExpiresIn != want.ExpiresIn || got.RefreshToken != want.RefreshToken ||
+		got.GrantType != want.GrantType || got.DeviceID != want.DeviceID || got.Username != want.Username ||
+		!got.Expiry.Equal(want.Expiry) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// the fields are stored under stable names
+	data, _ := ioutil.ReadFile(store.Path)
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	for _, key := range []string{"access_token", "expires_in", "grant_type", "device_id", "username"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("%s missing from %s", key, data)
+		}
+	}
+	if fields["expires_in"] != float64(3600) {
+		t.Errorf("got expires_in %v", fields["expires_in"])
+	}
+}
+
+// newTokenServer starts a server that issues a new token for every
+// request to the token endpoint, counting them
+func newTokenServer(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(requests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token" + string(rune('0'+n)),
+			"expires_in":   3600,
+		})
+	}))
+}
+
+// newStoreAPI creates an API for the user using the token server and
+// store
+func newStoreAPI(srv *httptest.Server, username string, store TokenStore) *RedditAPI {
+	api := NewRedditAPI("id", "secret", "test", username, false)
+	api.RedditBaseURL, _ = url.Parse(srv.URL)
+	api.Account.Store = store
+	return api
+}
+
+func TestPasswordLoginReusesToken(t *testing.T) {
+	var requests int32
+	srv := newTokenServer(&requests)
+	defer srv.Close()
+	store := &MemoryTokenStore{}
+
+	first := newStoreAPI(srv, "bot", store)
+	if err := first.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	again := newStoreAPI(srv, "Bot", store)
+	if err := again.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 || again.Account.Token.Token != first.Account.Token.Token {
+		t.Errorf("stored token not reused: %d requests", requests)
+	}
+
+	// another account sharing the store must not use the token
+	other := newStoreAPI(srv, "other", store)
+	if err := other.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || other.Account.Token.Token == first.Account.Token.Token {
+		t.Errorf("another user's token was reused: %d requests", requests)
+	}
+	if other.Account.Token.Username != "other" {
+		t.Errorf("got token username %q", other.Account.Token.Username)
+	}
+}
+
+func TestInstalledClientLoginDeviceID(t *testing.T) {
+	var requests int32
+	srv := newTokenServer(&requests)
+	defer srv.Close()
+	store := &MemoryTokenStore{}
+
+	first := newStoreAPI(srv, "", store)
+	if err := first.Account.InstalledClientLogin("device-one"); err != nil {
+		t.Fatal(err)
+	}
+	if first.Account.DeviceID != "device-one" {
+		t.Errorf("got device ID %q", first.Account.DeviceID)
+	}
+
+	same := newStoreAPI(srv, "", store)
+	if err := same.Account.InstalledClientLogin("device-one"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 || same.Account.DeviceID != "device-one" {
+		t.Errorf("stored token not reused: %d requests", requests)
+	}
+
+	other := newStoreAPI(srv, "", store)
+	if err := other.Account.InstalledClientLogin("device-two"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 || other.Account.DeviceID != "device-two" {
+		t.Errorf("token for another device was reused: %d requests", requests)
+	}
+}
+
+func TestRestoreToken(t *testing.T) {
+	store := &MemoryTokenStore{}
+	api := NewRedditAPI("id", "secret", "test", "bot", false)
+	api.Account.Store = store
+	if err := api.Account.RestoreToken(); err != ErrNoStoredToken {
+		t.Fatalf("got %v from an empty store", err)
+	}
+
+	store.Save(&Token{
+		Token:        "a1",
+		RefreshToken: "r1",
+		GrantType:    GrantTypeAuthorizationCode,
+		Username:     "bot",
+		Expiry:       time.Now().Add(-time.Hour),
+	})
+	if err := api.Account.RestoreToken(); err != nil {
+		t.Fatal(err)
+	}
+
+	other := NewRedditAPI("id", "secret", "test", "other", false)
+	other.Account.Store = store
+	if err := other.Account.RestoreToken(); err != ErrNoStoredToken {
+		t.Errorf("got %v restoring another user's token", err)
+	}
+}
+
+func TestSharedStoreKeepsGrantType(t *testing.T) {
+	var requests int32
+	srv := newTokenServer(&requests)
+	defer srv.Close()
+	store := &MemoryTokenStore{}
+
+	user := newStoreAPI(srv, "bot", store)
+	if err := user.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	app := newStoreAPI(srv, "bot", store)
+	if err := app.Account.ClientCredentialsLogin(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the app-only token saved by the other account must not replace
+	// the user token when it is about to expire
+	user.Account.Token.Expiry = time.Now().Add(tokenRefreshMargin / 2)
+	token, err := user.Account.accessToken(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AppOnly() || token.GrantType != GrantTypePassword || token.Token == app.Account.Token.Token {
+		t.Errorf("got token %+v", token)
+	}
+}
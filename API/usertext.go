@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// maxCommentLen is the longest comment reddit will accept
+const maxCommentLen = 10000
+
+// validateUserText checks the markdown for a comment or edit
+func validateUserText(text string, maxLen int) error {
+	if strings.TrimSpace(text) == "" {
+		return &ValidationError{Field: "text", Message: "empty"}
+	}
+	if len([]rune(text)) > maxLen {
+		return &ValidationError{Field: "text", Message: fmt.Sprintf("longer than %d characters", maxLen)}
+	}
+	return nil
+}
+
+// postUserText sends markdown to a JSON API endpoint that returns the
+// thing it created or changed
+func (api *RedditAPI) postUserText(ctx context.Context, endpoint string, name Fullname, text string, idempotent bool) (*Thing, error) {
+	u := api.GetOauthURL(endpoint)
+
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"thing_id": {string(name)},
+		"text":     {text},
+		"raw_json": {"1"},
+	}
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, idempotent)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response thingsResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return nil, err
+	}
+	if err := response.Error(); err != nil {
+		return nil, err
+	}
+	if len(response.JSON.Data.Things) == 0 {
+		return nil, errors.New("no thing returned")
+	}
+
+	return &response.JSON.Data.Things[0], nil
+}
+
+// Reply posts a comment replying to a post or comment, returning the
+// new comment
+func (api *RedditAPI) Reply(parent Fullname, markdown string) (*CommentResponse, error) {
+	return api.ReplyContext(context.Background(), parent, markdown)
+}
+
+// ReplyContext is like Reply but with a context
+func (api *RedditAPI) ReplyContext(ctx context.Context, parent Fullname, markdown string) (*CommentResponse, error) {
+	if err := checkFullname(parent, KindPost, KindComment); err != nil {
+		return nil, err
+	}
+	if err := validateUserText(markdown, maxCommentLen); err != nil {
+		return nil, err
+	}
+
+	thing, err := api.postUserText(ctx, OauthEndpointComment, parent, markdown, false)
+	if err != nil {
+		return nil, err
+	}
+	comment, ok := thing.Comment()
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("unexpected kind: %s", thing.Kind))
+	}
+	return comment, nil
+}
+
+// Edit replaces the text of a comment or self post, returning the
+// edited thing, which holds a *CommentResponse or *PostResponse
+func (api *RedditAPI) Edit(name Fullname, markdown string) (*Thing, error) {
+	return api.EditContext(context.Background(), name, markdown)
+}
+
+// EditContext is like Edit but with a context
+func (api *RedditAPI) EditContext(ctx context.Context, name Fullname, markdown string) (*Thing, error) {
+	if err := checkFullname(name, KindPost, KindComment); err != nil {
+		return nil, err
+	}
+	maxLen := maxCommentLen
+	if name.IsPost() {
+		maxLen = maxSelfTextLen
+	}
+	if err := validateUserText(markdown, maxLen); err != nil {
+		return nil, err
+	}
+
+	return api.postUserText(ctx, OauthEndpointEditUserText, name, markdown, true)
+}
+
+// Delete deletes a post or comment made by the user
+func (api *RedditAPI) Delete(name Fullname) error {
+	return api.DeleteContext(context.Background(), name)
+}
+
+// DeleteContext is like Delete but with a context
+func (api *RedditAPI) DeleteContext(ctx context.Context, name Fullname) error {
+	if err := checkFullname(name, KindPost, KindComment); err != nil {
+		return err
+	}
+	u := api.GetOauthURL(OauthEndpointDelete)
+
+	// construct post data
+	data := url.Values{
+		"api_type": {"json"},
+		"id":       {string(name)},
+	}
+
+	// send request
+	resp, err := api.postForm(ctx, u, data, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var response deleteResponse
+	if err := decodeResponse(resp, &response); err != nil {
+		return err
+	}
+	return response.Error()
+}
@@ -0,0 +1,76 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+func TestReplyEditDelete(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "bot", "title", "body")
+	postName := reddit.Fullname(post.Fullname())
+
+	comment, err := api.Reply(postName, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment.Body != "first" || comment.ParentID != postName {
+		t.Errorf("got %+v", comment)
+	}
+	reply, err := api.Reply(comment.Name, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.ParentID != comment.Name {
+		t.Errorf("got parent %s, want %s", reply.ParentID, comment.Name)
+	}
+
+	thing, err := api.Edit(comment.Name, "edited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited, ok := thing.Comment(); !ok || edited.Body != "edited" || time.Time(edited.Edited).IsZero() {
+		t.Errorf("got %+v after editing", thing.Data)
+	}
+	thing, err = api.Edit(postName, "new body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited, ok := thing.Post(); !ok || edited.Body != "new body" {
+		t.Errorf("got %+v after editing the post", thing.Data)
+	}
+
+	// only the author can edit
+	other := login(t, s, "other")
+	if _, err := other.Edit(comment.Name, "mine now"); !errors.Is(err, reddit.ErrCodeNotAuthor) {
+		t.Errorf("got %v, want NOT_AUTHOR", err)
+	}
+
+	if err := api.Delete(comment.Name); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := s.Comment(comment.ID); !stored.Deleted {
+		t.Error("comment not deleted")
+	}
+	if _, err := api.Reply(comment.Name, "too late"); !errors.Is(err, reddit.ErrCodeDeletedComment) {
+		t.Errorf("got %v, want DELETED_COMMENT", err)
+	}
+}
+
+func TestReplyValidation(t *testing.T) {
+	s, api := newFakeReddit(t)
+	defer s.Close()
+	post := s.AddPost("test", "bot", "title", "body")
+
+	if _, err := api.Reply("t5_abc", "hi"); !errors.Is(err, reddit.ErrInvalidFullname) {
+		t.Errorf("got %v replying to a subreddit, want ErrInvalidFullname", err)
+	}
+	var validationErr *reddit.ValidationError
+	if _, err := api.Reply(reddit.Fullname(post.Fullname()), ""); !errors.As(err, &validationErr) {
+		t.Errorf("got %v for an empty reply, want a *ValidationError", err)
+	}
+}
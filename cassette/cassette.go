@@ -0,0 +1,387 @@
+// Package cassette records HTTP interactions with reddit to a file
+// and replays them later, so that code using the API package can be
+// tested against real responses without network access. Tokens,
+// passwords and client secrets are scrubbed before anything is
+// written. Cassettes are stored as YAML if the file name ends in .yaml
+// or .yml, and as JSON otherwise.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Mode sets whether a Recorder records or replays
+type Mode int
+
+// recorder modes
+const (
+	// ModeReplay serves responses from the cassette and never
+	// touches the network
+	ModeReplay Mode = iota
+	// ModeRecord sends requests for real and records them
+	ModeRecord
+)
+
+// redacted replaces scrubbed values
+const redacted = "REDACTED"
+
+// sensitive form fields and JSON keys
+var sensitiveFields = []string{
+	"password",
+	"client_secret",
+	"access_token",
+	"refresh_token",
+	"code",
+}
+
+// sensitive headers are replaced, and dropped headers are removed
+// entirely
+var (
+	sensitiveHeaders = []string{"Authorization"}
+	droppedHeaders   = []string{"Cookie", "Set-Cookie"}
+)
+
+// Cassette is a recorded set of interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is a request and the response it received
+type Interaction struct {
+	Request  Request  `json:"request" yaml:"request"`
+	Response Response `json:"response" yaml:"response"`
+}
+
+// Request is a recorded request
+type Request struct {
+	Method  string      `json:"method" yaml:"method"`
+	URL     string      `json:"url" yaml:"url"`
+	Headers http.Header `json:"headers" yaml:"headers"`
+	Body    string      `json:"body" yaml:"body"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"status_code" yaml:"status_code"`
+	Headers    http.Header `json:"headers" yaml:"headers"`
+	Body       string      `json:"body" yaml:"body"`
+}
+
+// Recorder is an http.RoundTripper that records or replays
+// interactions. Set it as the Transport of RedditAPI.Client.
+type Recorder struct {
+	Path string
+	Mode Mode
+	// Transport makes the real requests when recording. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New creates a Recorder for the cassette at path. In replay mode the
+// cassette is loaded immediately.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		Path: path,
+		Mode: mode,
+	}
+	if mode == ModeReplay {
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// isYAML reports whether the cassette is stored as YAML
+func (r *Recorder) isYAML() bool {
+	switch strings.ToLower(filepath.Ext(r.Path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// load reads the cassette from Path
+func (r *Recorder) load() error {
+	data, err := ioutil.ReadFile(r.Path)
+	if err != nil {
+		return err
+	}
+	if r.isYAML() {
+		err = yaml.Unmarshal(data, &r.cassette)
+	} else {
+		err = json.Unmarshal(data, &r.cassette)
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to decode cassette: %s", err))
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return nil
+}
+
+// Save writes the recorded interactions to Path
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		data []byte
+		err  error
+	)
+	if r.isYAML() {
+		data, err = yaml.Marshal(r.cassette)
+	} else {
+		data, err = json.MarshalIndent(r.cassette, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.Path, data, 0644)
+}
+
+// RoundTrip records or replays a request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	// read the body so that it can be recorded or matched
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	if r.Mode == ModeRecord {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+// record sends the request and records the interaction
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     scrubURL(req.URL),
+			Headers: scrubHeaders(req.Header),
+			Body:    scrubBody(req.Header.Get("Content-Type"), body),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    scrubHeaders(resp.Header),
+			Body:       scrubBody(resp.Header.Get("Content-Type"), respBody),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.used = append(r.used, true)
+
+	return resp, nil
+}
+
+// replay returns the response of the first unused interaction that
+// matches the request
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !matches(&interaction.Request, req, body) {
+			continue
+		}
+		r.used[i] = true
+
+		recorded := interaction.Response
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+			StatusCode:    recorded.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        cloneHeader(recorded.Headers),
+			Body:          ioutil.NopCloser(strings.NewReader(recorded.Body)),
+			ContentLength: int64(len(recorded.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("cassette: no recorded interaction for %s %s", req.Method, req.URL.Path))
+}
+
+// matches compares a request against a recorded one by method, path,
+// query and body. The request is scrubbed first so that it can match
+// the scrubbed recording.
+func matches(recorded *Request, req *http.Request, body []byte) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+
+	u, err := url.Parse(recorded.URL)
+	if err != nil || u.Path != req.URL.Path {
+		return false
+	}
+	if !reflect.DeepEqual(scrubValues(u.Query()), scrubValues(req.URL.Query())) {
+		return false
+	}
+
+	scrubbed := scrubBody(req.Header.Get("Content-Type"), body)
+	if isForm(req.Header.Get("Content-Type")) {
+		recordedForm, err1 := url.ParseQuery(recorded.Body)
+		form, err2 := url.ParseQuery(scrubbed)
+		return err1 == nil && err2 == nil && reflect.DeepEqual(recordedForm, form)
+	}
+	return recorded.Body == scrubbed
+}
+
+/* Scrubbing */
+
+// isForm reports whether a content type is a URL-encoded form
+func isForm(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/x-www-form-urlencoded")
+}
+
+// isSensitive reports whether a form field or JSON key is sensitive
+func isSensitive(key string) bool {
+	for _, field := range sensitiveFields {
+		if strings.EqualFold(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubURL redacts sensitive query parameters
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	scrubbed.User = nil
+	scrubbed.RawQuery = scrubValues(u.Query()).Encode()
+	return scrubbed.String()
+}
+
+// scrubValues returns a copy of the values with sensitive fields
+// redacted
+func scrubValues(values url.Values) url.Values {
+	scrubbed := url.Values{}
+	for key, vals := range values {
+		if isSensitive(key) {
+			scrubbed[key] = []string{redacted}
+			continue
+		}
+		scrubbed[key] = append([]string(nil), vals...)
+	}
+	return scrubbed
+}
+
+// scrubHeaders returns a copy of the headers with credentials
+// removed
+func scrubHeaders(header http.Header) http.Header {
+	scrubbed := cloneHeader(header)
+	for _, key := range sensitiveHeaders {
+		if scrubbed.Get(key) != "" {
+			scrubbed.Set(key, redacted)
+		}
+	}
+	for _, key := range droppedHeaders {
+		scrubbed.Del(key)
+	}
+	return scrubbed
+}
+
+// scrubBody redacts sensitive fields in a form or JSON body
+func scrubBody(contentType string, body []byte) string {
+	if isForm(contentType) {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		return scrubValues(values).Encode()
+	}
+
+	// only rewrite JSON if there is something to redact, so that
+	// recorded responses stay as close to the original as possible
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return string(body)
+	}
+	if !scrubJSON(v) {
+		return string(body)
+	}
+	scrubbed, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(scrubbed)
+}
+
+// scrubJSON redacts sensitive keys in decoded JSON, returning true if
+// anything was changed
+func scrubJSON(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if _, ok := val.(string); ok && isSensitive(key) {
+				v[key] = redacted
+				changed = true
+				continue
+			}
+			if scrubJSON(val) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			if scrubJSON(val) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// cloneHeader copies a header
+func cloneHeader(header http.Header) http.Header {
+	clone := http.Header{}
+	for key, vals := range header {
+		clone[key] = append([]string(nil), vals...)
+	}
+	return clone
+}
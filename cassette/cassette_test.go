@@ -0,0 +1,87 @@
+package cassette_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joshbarrass/goreddit/cassette"
+	"github.com/joshbarrass/goreddit/fakereddit"
+)
+
+func TestRecordReplay(t *testing.T) {
+	for _, name := range []string{"cassette.json", "cassette.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "cassette")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			testRecordReplay(t, filepath.Join(dir, name))
+		})
+	}
+}
+
+// testRecordReplay records a session against a fake reddit to path,
+// then replays it with the server gone
+func testRecordReplay(t *testing.T, path string) {
+	s := fakereddit.NewServer("id", "hunter3")
+	defer s.Close()
+	s.AddUser("bot", "hunter2")
+	s.AddSubreddit("test", "bot")
+
+	// record
+	recorder, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := s.NewAPI("bot")
+	api.Client.Transport = recorder
+	if err := api.Account.PasswordLogin("hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := api.RequestSubmitTextPost("test", "title", "body", false, false, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// secrets are scrubbed
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "hunter3", api.Account.Token.Token} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+
+	// replay
+	player, err := cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api = s.NewAPI("bot")
+	api.Client.Transport = player
+	if err := api.Account.PasswordLogin("another password"); err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := api.RequestSubmitTextPost("test", "title", "body", false, false, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.ID != recorded.ID {
+		t.Errorf("got post %s, want %s", replayed.ID, recorded.ID)
+	}
+
+	// each interaction is only replayed once
+	if _, err := api.RequestSubmitTextPost("test", "title", "body", false, false, false, true); err == nil {
+		t.Error("an interaction was replayed twice")
+	}
+}
@@ -0,0 +1,243 @@
+package fakereddit
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxReportReasonLength is the longest report reason reddit accepts
+const maxReportReasonLength = 100
+
+// thingScore returns the score of the post or comment with the given
+// fullname, or nil if there is none. s.mu must be held.
+func (s *Server) thingScore(name string) *int64 {
+	kind, id := splitFullname(name)
+	switch kind {
+	case "t3":
+		if post, ok := s.posts[id]; ok {
+			return &post.Score
+		}
+	case "t1":
+		if comment, ok := s.comments[id]; ok {
+			return &comment.Score
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleVote(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	name := r.Form.Get("id")
+	dir, err := strconv.Atoi(r.Form.Get("dir"))
+	if err != nil || dir < -1 || dir > 1 {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	score := s.thingScore(name)
+	if score == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	// replace any earlier vote
+	*score += int64(dir - user.Votes[name])
+	if dir == 0 {
+		delete(user.Votes, name)
+	} else {
+		user.Votes[name] = dir
+	}
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) handleSave(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	name := r.Form.Get("id")
+	if s.thingScore(name) == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	unsave(user, name)
+	user.Saved = append(user.Saved, SavedThing{
+		Fullname: name,
+		Category: r.Form.Get("category"),
+	})
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) handleUnsave(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	name := r.Form.Get("id")
+	if s.thingScore(name) == nil {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	unsave(user, name)
+	writeJSON(w, map[string]interface{}{})
+}
+
+// unsave removes a thing from the user's saved things
+func unsave(user *User, name string) {
+	saved := user.Saved[:0]
+	for _, thing := range user.Saved {
+		if thing.Fullname != name {
+			saved = append(saved, thing)
+		}
+	}
+	user.Saved = saved
+}
+
+// handleHide returns a handler that hides or unhides a comma-separated
+// list of posts
+func (s *Server) handleHide(hide bool) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		names := strings.Split(r.Form.Get("id"), ",")
+		for _, name := range names {
+			kind, id := splitFullname(name)
+			if _, ok := s.posts[id]; !ok || kind != "t3" {
+				writeStatus(w, http.StatusBadRequest)
+				return
+			}
+		}
+
+		for _, name := range names {
+			hidden := user.Hidden[:0]
+			for _, h := range user.Hidden {
+				if h != name {
+					hidden = append(hidden, h)
+				}
+			}
+			user.Hidden = hidden
+			if hide {
+				user.Hidden = append(user.Hidden, name)
+			}
+		}
+		writeJSON(w, map[string]interface{}{})
+	}
+}
+
+func (s *Server) handleReport(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	// the most specific reason given is recorded
+	var reason string
+	for _, field := range []string{"reason", "rule_reason", "custom_text"} {
+		text := r.Form.Get(field)
+		if len([]rune(text)) > maxReportReasonLength {
+			writeJSONErrors(w, []string{"TOO_LONG", "this is too long (max: 100)", field})
+			return
+		}
+		if text != "" {
+			reason = text
+		}
+	}
+	if reason == "" {
+		writeJSONErrors(w, []string{"NO_TEXT", "we need something here", "reason"})
+		return
+	}
+	report := Report{Reason: reason, User: user.Name}
+
+	// a new report puts approved items back in the modqueue
+	kind, id := splitFullname(r.Form.Get("thing_id"))
+	switch kind {
+	case "t3":
+		if post, ok := s.posts[id]; ok {
+			post.Reports = append(post.Reports, report)
+			post.Approved = false
+			writeJSONErrors(w)
+			return
+		}
+	case "t1":
+		if comment, ok := s.comments[id]; ok {
+			comment.Reports = append(comment.Reports, report)
+			comment.Approved = false
+			writeJSONErrors(w)
+			return
+		}
+	}
+	writeJSONErrors(w, []string{"NO_THING_ID", "no thing found", "thing_id"})
+}
+
+// handleUserSaved lists the things saved by the user, most recently
+// saved first. Only the user can see them.
+func (s *Server) handleUserSaved(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	if !strings.EqualFold(params[0], user.Name) {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+
+	var things []interface{}
+	var names []string
+	for i := len(user.Saved) - 1; i >= 0; i-- {
+		name := user.Saved[i].Fullname
+		kind, id := splitFullname(name)
+		switch kind {
+		case "t3":
+			things = append(things, s.postThing(s.posts[id], user))
+		case "t1":
+			thing := s.commentThing(s.comments[id], 0, user)
+			thing["data"].(map[string]interface{})["replies"] = ""
+			things = append(things, thing)
+		default:
+			continue
+		}
+		names = append(names, name)
+	}
+	writeJSON(w, paginate(r, things, names))
+}
+
+// handleUserHidden lists the posts hidden by the user, most recently
+// hidden first. Only the user can see them.
+func (s *Server) handleUserHidden(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	if !strings.EqualFold(params[0], user.Name) {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+
+	var things []interface{}
+	var names []string
+	for i := len(user.Hidden) - 1; i >= 0; i-- {
+		_, id := splitFullname(user.Hidden[i])
+		things = append(things, s.postThing(s.posts[id], user))
+		names = append(names, user.Hidden[i])
+	}
+	writeJSON(w, paginate(r, things, names))
+}
+
+// isSaved reports whether the user, who may be nil, saved a thing
+func isSaved(user *User, name string) bool {
+	if user == nil {
+		return false
+	}
+	for _, thing := range user.Saved {
+		if thing.Fullname == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isHidden reports whether the user, who may be nil, hid a post
+func isHidden(user *User, name string) bool {
+	if user == nil {
+		return false
+	}
+	for _, hidden := range user.Hidden {
+		if hidden == name {
+			return true
+		}
+	}
+	return false
+}
+
+// likes returns true for an upvote, false for a downvote, or nil if
+// the user, who may be nil, has not voted, as reddit does
+func likes(user *User, name string) interface{} {
+	if user == nil {
+		return nil
+	}
+	switch user.Votes[name] {
+	case 1:
+		return true
+	case -1:
+		return false
+	}
+	return nil
+}
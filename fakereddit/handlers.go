@@ -0,0 +1,909 @@
+package fakereddit
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+// limits on submissions
+const (
+	maxTitleLength     = 300
+	maxFlairTextLength = 64
+)
+
+// eventTimeFormat is the format of event start and end times
+const eventTimeFormat = "2006-01-02T15:04:05"
+
+// routes returns the OAuth endpoints
+func (s *Server) routes() []route {
+	return []route{
+		{method: http.MethodGet, pattern: reddit.OauthEndpointMe, handler: s.handleMe},
+		{method: http.MethodGet, pattern: "/r/*/stylesheet", handler: s.handleStylesheet},
+		{method: http.MethodGet, pattern: "/r/*/about/stylesheet", handler: s.handleStylesheetTemplate},
+		{method: http.MethodPost, pattern: "/r/*/api/subreddit_stylesheet", handler: s.handleSetStylesheet, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointSubmitPost, handler: s.handleSubmit, write: true},
+		{method: http.MethodPost, pattern: "/api/submit_gallery_post", handler: s.handleSubmitGallery, write: true},
+		{method: http.MethodPost, pattern: "/api/submit_poll_post", handler: s.handleSubmitPoll, write: true},
+		{method: http.MethodPost, pattern: "/api/media/asset", handler: s.handleMediaAsset, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointRequestSticky, handler: s.handleSticky, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointRequestContestMode, handler: s.handleContestMode, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointRequestRemovePost, handler: s.handleRemove, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointRemovalReasons, handler: s.handleRemovalReasons, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointApprove, handler: s.handleApprove, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointIgnoreReports, handler: s.handleIgnoreReports(true), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointUnignoreReports, handler: s.handleIgnoreReports(false), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointLock, handler: s.handleLock(true), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointUnlock, handler: s.handleLock(false), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointMarkNSFW, handler: s.handleNSFW(true), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointUnmarkNSFW, handler: s.handleNSFW(false), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointSpoiler, handler: s.handleSpoiler(true), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointUnspoiler, handler: s.handleSpoiler(false), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointDistinguish, handler: s.handleDistinguish, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointSetSuggestedSort, handler: s.handleSuggestedSort, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointComposeMessage, handler: s.handleCompose, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointComment, handler: s.handleComment, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointEditUserText, handler: s.handleEditUserText, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointDelete, handler: s.handleDelete, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointVote, handler: s.handleVote, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointSave, handler: s.handleSave, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointUnsave, handler: s.handleUnsave, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointHide, handler: s.handleHide(true), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointUnhide, handler: s.handleHide(false), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointReport, handler: s.handleReport, write: true},
+		{method: http.MethodGet, pattern: "/user/*/saved", handler: s.handleUserSaved, write: true},
+		{method: http.MethodGet, pattern: "/user/*/hidden", handler: s.handleUserHidden, write: true},
+		{method: http.MethodGet, pattern: "/r/*/comments/*", handler: s.handlePost},
+		{method: http.MethodGet, pattern: "/r/*/comments/*/*", handler: s.handlePost},
+		{method: http.MethodGet, pattern: "/comments/*", handler: s.handlePost},
+		{method: http.MethodGet, pattern: "/comments/*/*/*", handler: s.handleCommentThread},
+		{method: http.MethodGet, pattern: reddit.OauthEndpointMoreChildren, handler: s.handleMoreChildren},
+		{method: http.MethodGet, pattern: "/r/*/hot", handler: s.handleSubredditListing(reddit.SortHot)},
+		{method: http.MethodGet, pattern: "/r/*/new", handler: s.handleSubredditListing(reddit.SortNew)},
+		{method: http.MethodGet, pattern: "/r/*/top", handler: s.handleSubredditListing(reddit.SortTop)},
+		{method: http.MethodGet, pattern: "/r/*/rising", handler: s.handleSubredditListing(reddit.SortRising)},
+		{method: http.MethodGet, pattern: "/r/*/controversial", handler: s.handleSubredditListing(reddit.SortControversial)},
+		{method: http.MethodGet, pattern: "/r/*/about/modqueue", handler: s.handleModListing(reddit.ModQueue)},
+		{method: http.MethodGet, pattern: "/r/*/about/log", handler: s.handleModLog},
+		{method: http.MethodGet, pattern: "/r/*/about/banned", handler: s.handleRelationshipListing(reddit.RelBanned)},
+		{method: http.MethodGet, pattern: "/r/*/about/muted", handler: s.handleRelationshipListing(reddit.RelMuted)},
+		{method: http.MethodGet, pattern: "/r/*/about/contributors", handler: s.handleRelationshipListing(reddit.RelContributor)},
+		{method: http.MethodGet, pattern: "/r/*/about/moderators", handler: s.handleRelationshipListing(reddit.RelModerator)},
+		{method: http.MethodPost, pattern: "/r/*/api/friend", handler: s.handleFriend, write: true},
+		{method: http.MethodPost, pattern: "/r/*/api/unfriend", handler: s.handleUnfriend, write: true},
+		{method: http.MethodPost, pattern: "/r/*/api/setpermissions", handler: s.handleSetPermissions, write: true},
+		{method: http.MethodPost, pattern: "/r/*/api/accept_moderator_invite", handler: s.handleAcceptModInvite, write: true},
+		{method: http.MethodGet, pattern: "/r/*/about/reports", handler: s.handleModListing(reddit.ModReports)},
+		{method: http.MethodGet, pattern: "/r/*/about/spam", handler: s.handleModListing(reddit.ModSpam)},
+		{method: http.MethodGet, pattern: "/r/*/about/edited", handler: s.handleModListing(reddit.ModEdited)},
+		{method: http.MethodGet, pattern: "/r/*/about/unmoderated", handler: s.handleModListing(reddit.ModUnmoderated)},
+		{method: http.MethodGet, pattern: "/r/*/comments", handler: s.handleSubredditComments},
+		{method: http.MethodGet, pattern: reddit.OauthEndpointModmail, handler: s.handleModmail, write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointModmailRead, handler: s.handleModmailMark(true), write: true},
+		{method: http.MethodPost, pattern: reddit.OauthEndpointModmailUnread, handler: s.handleModmailMark(false), write: true},
+		{method: http.MethodGet, pattern: "/api/mod/conversations/*", handler: s.handleModmailThread, write: true},
+		{method: http.MethodPost, pattern: "/api/mod/conversations/*", handler: s.handleModmailReply, write: true},
+		{method: http.MethodPost, pattern: "/api/mod/conversations/*/archive", handler: s.handleModmailAction(reddit.ModmailActionArchive), write: true},
+		{method: http.MethodPost, pattern: "/api/mod/conversations/*/unarchive", handler: s.handleModmailAction(reddit.ModmailActionUnarchive), write: true},
+		{method: http.MethodPost, pattern: "/api/mod/conversations/*/highlight", handler: s.handleModmailAction(reddit.ModmailActionHighlight), write: true},
+		{method: http.MethodDelete, pattern: "/api/mod/conversations/*/highlight", handler: s.handleModmailAction(reddit.ModmailActionUnhighlight), write: true},
+		{method: http.MethodPost, pattern: "/api/mod/conversations/*/mute", handler: s.handleModmailAction(reddit.ModmailActionMute), write: true},
+		{method: http.MethodPost, pattern: "/api/mod/conversations/*/unmute", handler: s.handleModmailAction(reddit.ModmailActionUnmute), write: true},
+	}
+}
+
+/* Authentication */
+
+// handleAccessToken issues tokens for each of the supported grants
+func (s *Server) handleAccessToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeStatus(w, http.StatusMethodNotAllowed)
+		return
+	}
+
+	// check client credentials
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeStatus(w, http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		username  string
+		permanent bool
+	)
+	switch r.Form.Get("grant_type") {
+	case reddit.GrantTypePassword:
+		user, ok := s.users[strings.ToLower(r.Form.Get("username"))]
+		if !ok || user.password != r.Form.Get("password") {
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		username = user.Name
+	case reddit.GrantTypeAuthorizationCode:
+		code := r.Form.Get("code")
+		name, ok := s.codes[code]
+		if !ok {
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(s.codes, code)
+		username = name
+		permanent = true
+	case reddit.GrantTypeRefreshToken:
+		sess, ok := s.refreshTokens[r.Form.Get("refresh_token")]
+		if !ok {
+			writeJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		username = sess.username
+	case reddit.GrantTypeClientCredentials:
+		// app-only, no user
+	case reddit.GrantTypeInstalledClient:
+		if r.Form.Get("device_id") == "" {
+			writeJSON(w, map[string]string{"error": "invalid_request"})
+			return
+		}
+	default:
+		writeJSON(w, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	token := s.newToken()
+	s.tokens[token] = &session{
+		username: username,
+		expiry:   time.Now().Add(s.TokenLifetime),
+	}
+	response := map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   int64(s.TokenLifetime / time.Second),
+		"scope":        "*",
+	}
+	if permanent {
+		refreshToken := s.newToken()
+		s.refreshTokens[refreshToken] = &session{username: username}
+		response["refresh_token"] = refreshToken
+	}
+	writeJSON(w, response)
+}
+
+// AddAuthCode registers a code that can be exchanged for a token for
+// the given user, as if they had approved the app on the authorize
+// page
+func (s *Server) AddAuthCode(code, username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = username
+}
+
+// ExpireTokens makes all issued access tokens expire immediately
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.tokens {
+		sess.expiry = time.Now()
+	}
+}
+
+// newToken returns a new random-looking token. s.mu must be held.
+func (s *Server) newToken() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("token-%s-%d", s.newID(), time.Now().UnixNano())))
+}
+
+/* Account */
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	if user == nil {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+
+	isMod := false
+	for _, sub := range s.subreddits {
+		if s.isModerator(user, sub.Name) {
+			isMod = true
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"name":               user.Name,
+		"id":                 user.ID,
+		"created":            float64(user.Created.Unix()),
+		"created_utc":        float64(user.Created.Unix()),
+		"comment_karma":      0,
+		"link_karma":         0,
+		"has_mail":           len(user.Inbox) > 0,
+		"has_mod_mail":       false,
+		"has_verified_email": true,
+		"is_gold":            false,
+		"is_mod":             isMod,
+		"over_18":            false,
+	})
+}
+
+/* Stylesheets */
+
+func (s *Server) handleStylesheet(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.subreddits[strings.ToLower(params[0])]
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/css; charset=UTF-8")
+	w.Write([]byte(sub.Stylesheet))
+}
+
+func (s *Server) handleStylesheetTemplate(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.subreddits[strings.ToLower(params[0])]
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	images := []map[string]string{}
+	for _, image := range sub.Images {
+		images = append(images, map[string]string{
+			"name": image.Name,
+			"url":  image.URL,
+			"link": image.Link,
+		})
+	}
+	writeJSON(w, map[string]interface{}{
+		"kind": "stylesheet",
+		"data": map[string]interface{}{
+			"images":       images,
+			"stylesheet":   sub.Stylesheet,
+			"subreddit_id": "t5_" + sub.ID,
+		},
+	})
+}
+
+func (s *Server) handleSetStylesheet(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.subreddits[strings.ToLower(params[0])]
+	if !ok {
+		writeJSONErrors(w, []string{"SUBREDDIT_NOEXIST", "that subreddit doesn't exist", "sr"})
+		return
+	}
+	if !s.isModerator(user, sub.Name) {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+
+	switch r.Form.Get("op") {
+	case "save":
+		sub.Stylesheet = r.Form.Get("stylesheet_contents")
+	case "preview":
+	default:
+		writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "op"})
+		return
+	}
+	writeJSONErrors(w)
+}
+
+/* Posts */
+
+// checkSubmission checks the subreddit and title of a submission,
+// returning the subreddit or the error to report. s.mu must be held.
+func (s *Server) checkSubmission(subreddit, title string) (*Subreddit, []string) {
+	sub, ok := s.subreddits[strings.ToLower(subreddit)]
+	if !ok {
+		return nil, []string{"SUBREDDIT_NOEXIST", "that community doesn't exist", "sr"}
+	}
+	if title == "" {
+		return nil, []string{"NO_TEXT", "we need something here", "title"}
+	}
+	if len(title) > maxTitleLength {
+		return nil, []string{"TOO_LONG", fmt.Sprintf("this is too long (max: %d)", maxTitleLength), "title"}
+	}
+	return sub, nil
+}
+
+// submitOptions are the optional settings of a submission
+type submitOptions struct {
+	ad, nsfw, spoiler, sendReplies   bool
+	flairID, flairText, collectionID string
+	eventStart, eventEnd             time.Time
+}
+
+// parseSubmitOptions reads the optional settings of a submission,
+// returning the error to report if any are invalid
+func parseSubmitOptions(form url.Values) (*submitOptions, []string) {
+	opts := &submitOptions{
+		ad:           form.Get("ad") == "true",
+		nsfw:         form.Get("nsfw") == "true",
+		spoiler:      form.Get("spoiler") == "true",
+		sendReplies:  form.Get("sendreplies") != "false",
+		flairID:      form.Get("flair_id"),
+		flairText:    form.Get("flair_text"),
+		collectionID: form.Get("collection_id"),
+	}
+	if len(opts.flairText) > maxFlairTextLength {
+		return nil, []string{"TOO_LONG", fmt.Sprintf("this is too long (max: %d)", maxFlairTextLength), "flair_text"}
+	}
+
+	if form.Get("event_start") != "" || form.Get("event_end") != "" {
+		// Go accepts "" and "Local", which aren't timezone names
+		loc, err := time.LoadLocation(form.Get("event_tz"))
+		if err != nil || form.Get("event_tz") == "" || form.Get("event_tz") == "Local" {
+			return nil, []string{"INVALID_OPTION", "that option is not valid", "event_tz"}
+		}
+		opts.eventStart, err = time.ParseInLocation(eventTimeFormat, form.Get("event_start"), loc)
+		if err != nil {
+			return nil, []string{"INVALID_OPTION", "that option is not valid", "event_start"}
+		}
+		opts.eventEnd, err = time.ParseInLocation(eventTimeFormat, form.Get("event_end"), loc)
+		if err != nil || !opts.eventEnd.After(opts.eventStart) {
+			return nil, []string{"INVALID_OPTION", "that option is not valid", "event_end"}
+		}
+	}
+	return opts, nil
+}
+
+// apply sets the options on a post
+func (opts *submitOptions) apply(post *Post) {
+	post.Ad = opts.ad
+	post.NSFW = opts.nsfw
+	post.Spoiler = opts.spoiler
+	post.SendReplies = opts.sendReplies
+	post.FlairID = opts.flairID
+	post.FlairText = opts.flairText
+	post.CollectionID = opts.collectionID
+	post.EventStart = opts.eventStart
+	post.EventEnd = opts.eventEnd
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	title := r.Form.Get("title")
+	sub, errs := s.checkSubmission(r.Form.Get("sr"), title)
+	if errs != nil {
+		writeJSONErrors(w, errs)
+		return
+	}
+	opts, errs := parseSubmitOptions(r.Form)
+	if errs != nil {
+		writeJSONErrors(w, errs)
+		return
+	}
+
+	kind := r.Form.Get("kind")
+	var media, poster *MediaAsset
+	switch kind {
+	case "self":
+	case "link":
+		link := r.Form.Get("url")
+		if link == "" {
+			writeJSONErrors(w, []string{"NO_URL", "a url is required", "url"})
+			return
+		}
+		if !formBool(r, "resubmit") && s.linkSubmitted(sub.Name, link) {
+			writeJSONErrors(w, []string{"ALREADY_SUB", "that link has already been submitted", "url"})
+			return
+		}
+	case "image", "video", "videogif":
+		// media must have been uploaded first
+		var ok bool
+		if media, ok = s.mediaByURL(r.Form.Get("url")); !ok {
+			writeJSONErrors(w, []string{"BAD_URL", "you should check that url", "url"})
+			return
+		}
+		if kind != "image" {
+			if poster, ok = s.mediaByURL(r.Form.Get("video_poster_url")); !ok {
+				writeJSONErrors(w, []string{"BAD_URL", "you should check that url", "video_poster_url"})
+				return
+			}
+		}
+	default:
+		writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "kind"})
+		return
+	}
+
+	post := s.addPost(sub.Name, user.Name, title)
+	switch kind {
+	case "self":
+		post.IsSelf = true
+		post.Body = r.Form.Get("text")
+	case "link":
+		post.URL = r.Form.Get("url")
+	case "image":
+		post.URL = media.URL
+	default:
+		post.URL = media.URL
+		post.IsVideo = true
+		post.PosterURL = poster.URL
+	}
+	opts.apply(post)
+
+	writeJSONData(w, map[string]interface{}{
+		"url":          "https://www.reddit.com" + post.permalink(),
+		"drafts_count": 0,
+		"id":           post.ID,
+		"name":         post.Fullname(),
+	})
+}
+
+func (s *Server) handleSticky(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	_, id := splitFullname(r.Form.Get("id"))
+	post, ok := s.posts[id]
+	if !ok {
+		writeJSONErrors(w, []string{"NO_THING_ID", "no post found", "id"})
+		return
+	}
+	if !s.isModerator(user, post.Subreddit) {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+
+	post.Stickied = formBool(r, "state")
+	post.StickyNum = 0
+	if post.Stickied {
+		post.StickyNum, _ = strconv.Atoi(r.Form.Get("num"))
+		s.logModAction(user, reddit.ModActionSticky, post, nil, "")
+	} else {
+		s.logModAction(user, reddit.ModActionUnsticky, post, nil, "")
+	}
+	writeJSONErrors(w)
+}
+
+func (s *Server) handleContestMode(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	_, id := splitFullname(r.Form.Get("id"))
+	post, ok := s.posts[id]
+	if !ok {
+		writeJSONErrors(w, []string{"NO_THING_ID", "no post found", "id"})
+		return
+	}
+	if !s.isModerator(user, post.Subreddit) {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+
+	post.ContestMode = formBool(r, "state")
+	if post.ContestMode {
+		s.logModAction(user, reddit.ModActionSetContestMode, post, nil, "")
+	} else {
+		s.logModAction(user, reddit.ModActionUnsetContestMode, post, nil, "")
+	}
+	writeJSONErrors(w)
+}
+
+func (s *Server) handleRemove(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	kind, id := splitFullname(r.Form.Get("id"))
+	switch kind {
+	case "t3":
+		post, ok := s.posts[id]
+		if !ok {
+			writeStatus(w, http.StatusNotFound)
+			return
+		}
+		if !s.isModerator(user, post.Subreddit) {
+			writeStatus(w, http.StatusForbidden)
+			return
+		}
+		post.Removed = true
+		post.Spam = formBool(r, "spam")
+		post.Approved = false
+		if post.Spam {
+			s.logModAction(user, reddit.ModActionSpamLink, post, nil, "confirm_spam")
+		} else {
+			s.logModAction(user, reddit.ModActionRemoveLink, post, nil, "remove")
+		}
+	case "t1":
+		comment, ok := s.comments[id]
+		if !ok {
+			writeStatus(w, http.StatusNotFound)
+			return
+		}
+		if !s.isModerator(user, s.posts[comment.PostID].Subreddit) {
+			writeStatus(w, http.StatusForbidden)
+			return
+		}
+		comment.Removed = true
+		comment.Spam = formBool(r, "spam")
+		comment.Approved = false
+		if comment.Spam {
+			s.logModAction(user, reddit.ModActionSpamComment, nil, comment, "confirm_spam")
+		} else {
+			s.logModAction(user, reddit.ModActionRemoveComment, nil, comment, "remove")
+		}
+	default:
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]interface{}{})
+}
+
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	// the post ID follows the subreddit, if there is one
+	id := params[0]
+	if len(params) > 1 {
+		id = params[1]
+	}
+	post, ok := s.posts[id]
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, []interface{}{
+		listing([]interface{}{s.postThing(post, user)}),
+		listing(s.commentThings(post.Fullname(), 0, user)),
+	})
+}
+
+// handleCommentThread shows a post with only the thread below one
+// comment, as linked to by "continue this thread"
+func (s *Server) handleCommentThread(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	post, ok := s.posts[params[0]]
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	comment, ok := s.comments[params[2]]
+	if !ok || comment.PostID != post.ID {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, []interface{}{
+		listing([]interface{}{s.postThing(post, user)}),
+		listing([]interface{}{s.commentThing(comment, 0, user)}),
+	})
+}
+
+// handleMoreChildren returns the requested comments followed by all
+// of their replies as a flat list, as /api/morechildren does
+func (s *Server) handleMoreChildren(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	_, postID := splitFullname(r.Form.Get("link_id"))
+	if _, ok := s.posts[postID]; !ok {
+		writeJSONErrors(w, []string{"NO_THING_ID", "no post found", "link_id"})
+		return
+	}
+
+	things := []interface{}{}
+	var add func(comment *Comment)
+	add = func(comment *Comment) {
+		thing := s.commentThing(comment, 0, user)
+		thing["data"].(map[string]interface{})["replies"] = ""
+		things = append(things, thing)
+		for _, reply := range s.replies(comment.Fullname()) {
+			add(reply)
+		}
+	}
+	for _, id := range strings.Split(r.Form.Get("children"), ",") {
+		comment, ok := s.comments[id]
+		if !ok || comment.PostID != postID {
+			continue
+		}
+		if s.unloadable(id) {
+			things = append(things, moreThing(comment.ParentID, 0, 1, []string{id}))
+			continue
+		}
+		add(comment)
+	}
+
+	writeJSONData(w, map[string]interface{}{
+		"things": things,
+	})
+}
+
+// unloadable reports whether the comment is one of the
+// UnloadableComments. s.mu must be held.
+func (s *Server) unloadable(id string) bool {
+	for _, unloadable := range s.UnloadableComments {
+		if unloadable == id {
+			return true
+		}
+	}
+	return false
+}
+
+/* Messages */
+
+func (s *Server) handleCompose(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	to := r.Form.Get("to")
+	message := Message{
+		ID:      s.newID(),
+		From:    user.Name,
+		To:      to,
+		Subject: r.Form.Get("subject"),
+		Body:    r.Form.Get("text"),
+		Created: time.Now(),
+	}
+	if message.Subject == "" {
+		writeJSONErrors(w, []string{"NO_SUBJECT", "please enter a subject", "subject"})
+		return
+	}
+
+	if strings.HasPrefix(to, "/r/") {
+		sub, ok := s.subreddits[strings.ToLower(to[len("/r/"):])]
+		if !ok {
+			writeJSONErrors(w, []string{"SUBREDDIT_NOEXIST", "that subreddit doesn't exist", "to"})
+			return
+		}
+		sub.Modmail = append(sub.Modmail, message)
+		s.addConversation(sub, user.Name, message.Subject, message.Body)
+	} else {
+		name := strings.TrimPrefix(strings.TrimPrefix(to, "/u/"), "u/")
+		recipient, ok := s.users[strings.ToLower(name)]
+		if !ok {
+			writeJSONErrors(w, []string{"USER_DOESNT_EXIST", "that user doesn't exist", "to"})
+			return
+		}
+		recipient.Inbox = append(recipient.Inbox, message)
+	}
+	writeJSONErrors(w)
+}
+
+/* JSON representations */
+
+// nullString returns nil for an empty string, as reddit does for
+// unset fields
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// eventTime returns the timestamp of an event, or nil if it is unset
+func eventTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return float64(t.Unix())
+}
+
+// editedTime returns the time something was edited, or false if it
+// has not been, as reddit does
+func editedTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return false
+	}
+	return float64(t.Unix())
+}
+
+// deletedText replaces text with "[deleted]" if its thing was deleted
+func deletedText(text string, deleted bool) string {
+	if deleted {
+		return "[deleted]"
+	}
+	return text
+}
+
+// listing wraps things in a listing
+func listing(children []interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"kind": "Listing",
+		"data": map[string]interface{}{
+			"after":    nil,
+			"before":   nil,
+			"dist":     len(children),
+			"children": children,
+		},
+	}
+}
+
+// postThing returns the JSON representation of a post as seen by the
+// user, who may be nil. s.mu must be held.
+func (s *Server) postThing(post *Post, user *User) map[string]interface{} {
+	numComments := 0
+	for _, comment := range s.comments {
+		if comment.PostID == post.ID {
+			numComments++
+		}
+	}
+	userReports, modReports, numReports := s.reports(post.Reports, post.Subreddit, user)
+	return map[string]interface{}{
+		"kind": "t3",
+		"data": map[string]interface{}{
+			"id":                     post.ID,
+			"name":                   post.Fullname(),
+			"subreddit":              post.Subreddit,
+			"subreddit_id":           s.subredditFullname(post.Subreddit),
+			"subreddit_type":         "public",
+			"title":                  post.Title,
+			"author":                 deletedText(post.Author, post.Deleted),
+			"author_fullname":        s.authorFullname(deletedText(post.Author, post.Deleted)),
+			"selftext":               deletedText(post.Body, post.Deleted),
+			"url":                    post.URL,
+			"is_video":               post.IsVideo,
+			"is_gallery":             len(post.Gallery) > 0,
+			"gallery_data":           galleryData(post),
+			"poll_data":              pollData(post, user),
+			"link_flair_text":        nullString(post.FlairText),
+			"link_flair_template_id": nullString(post.FlairID),
+			"event_start":            eventTime(post.EventStart),
+			"event_end":              eventTime(post.EventEnd),
+			"permalink":              post.permalink(),
+			"is_self":                post.IsSelf,
+			"over_18":                post.NSFW,
+			"spoiler":                post.Spoiler,
+			"stickied":               post.Stickied,
+			"contest_mode":           post.ContestMode,
+			"locked":                 post.Locked,
+			"removed":                post.Removed,
+			"approved":               post.Approved,
+			"ignore_reports":         post.IgnoreReports,
+			"num_reports":            numReports,
+			"user_reports":           userReports,
+			"mod_reports":            modReports,
+			"distinguished":          nullString(post.Distinguished),
+			"suggested_sort":         nullString(post.SuggestedSort),
+			"archived":               false,
+			"quarantine":             false,
+			"saved":                  isSaved(user, post.Fullname()),
+			"hidden":                 isHidden(user, post.Fullname()),
+			"likes":                  likes(user, post.Fullname()),
+			"gilded":                 0,
+			"ups":                    post.Score,
+			"downs":                  0,
+			"score":                  post.Score,
+			"num_comments":           numComments,
+			"edited":                 editedTime(post.Edited),
+			"created_utc":            float64(post.Created.Unix()),
+		},
+	}
+}
+
+// replies returns the direct replies to the given fullname, oldest
+// first. s.mu must be held.
+func (s *Server) replies(parent string) []*Comment {
+	var children []*Comment
+	for _, comment := range s.comments {
+		if comment.ParentID == parent {
+			children = append(children, comment)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return idLess(children[i].ID, children[j].ID)
+	})
+	return children
+}
+
+// countReplies returns the number of comments below the given
+// fullname. s.mu must be held.
+func (s *Server) countReplies(parent string) int {
+	count := 0
+	for _, reply := range s.replies(parent) {
+		count += 1 + s.countReplies(reply.Fullname())
+	}
+	return count
+}
+
+// commentThings returns the replies to the given fullname with their
+// own replies nested, at the given depth. Replies beyond
+// MoreCommentsThreshold or MaxCommentDepth are replaced with "more"
+// stubs. s.mu must be held.
+func (s *Server) commentThings(parent string, depth int, user *User) []interface{} {
+	children := s.replies(parent)
+	things := []interface{}{}
+
+	// too deep, so link to the thread instead
+	if s.MaxCommentDepth > 0 && depth >= s.MaxCommentDepth {
+		if len(children) > 0 {
+			things = append(things, moreThing(parent, depth, s.countReplies(parent), []string{}))
+		}
+		return things
+	}
+
+	shown := children
+	if s.MoreCommentsThreshold > 0 && len(children) > s.MoreCommentsThreshold {
+		shown = children[:s.MoreCommentsThreshold]
+	}
+	for _, comment := range shown {
+		things = append(things, s.commentThing(comment, depth, user))
+	}
+
+	// stub the rest
+	if rest := children[len(shown):]; len(rest) > 0 {
+		ids := make([]string, len(rest))
+		count := 0
+		for i, comment := range rest {
+			ids[i] = comment.ID
+			count += 1 + s.countReplies(comment.Fullname())
+		}
+		things = append(things, moreThing(parent, depth, count, ids))
+	}
+	return things
+}
+
+// moreThing returns the JSON representation of a "more" stub. With no
+// children, it stands for a thread that is too deep to show.
+func moreThing(parent string, depth, count int, children []string) map[string]interface{} {
+	id := "_"
+	if len(children) > 0 {
+		id = children[0]
+	}
+	return map[string]interface{}{
+		"kind": "more",
+		"data": map[string]interface{}{
+			"count":     count,
+			"name":      "t1_" + id,
+			"id":        id,
+			"parent_id": parent,
+			"depth":     depth,
+			"children":  children,
+		},
+	}
+}
+
+// commentThing returns the JSON representation of a comment and its
+// replies as seen by the user, who may be nil, with the comment at the
+// given depth. s.mu must be held.
+func (s *Server) commentThing(comment *Comment, depth int, user *User) map[string]interface{} {
+	post := s.posts[comment.PostID]
+
+	// reddit uses an empty string rather than an empty listing
+	var replies interface{} = ""
+	if r := s.commentThings(comment.Fullname(), depth+1, user); len(r) > 0 {
+		replies = listing(r)
+	}
+
+	userReports, modReports, numReports := s.reports(comment.Reports, post.Subreddit, user)
+	return map[string]interface{}{
+		"kind": "t1",
+		"data": map[string]interface{}{
+			"id":              comment.ID,
+			"name":            comment.Fullname(),
+			"link_id":         post.Fullname(),
+			"parent_id":       comment.ParentID,
+			"subreddit":       post.Subreddit,
+			"subreddit_id":    s.subredditFullname(post.Subreddit),
+			"subreddit_type":  "public",
+			"author":          deletedText(comment.Author, comment.Deleted),
+			"author_fullname": s.authorFullname(deletedText(comment.Author, comment.Deleted)),
+			"body":            deletedText(comment.Body, comment.Deleted),
+			"permalink":       post.permalink() + comment.ID + "/",
+			"removed":         comment.Removed,
+			"approved":        comment.Approved,
+			"ignore_reports":  comment.IgnoreReports,
+			"num_reports":     numReports,
+			"user_reports":    userReports,
+			"mod_reports":     modReports,
+			"distinguished":   nullString(comment.Distinguished),
+			"archived":        false,
+			"locked":          comment.Locked,
+			"stickied":        comment.Stickied,
+			"saved":           isSaved(user, comment.Fullname()),
+			"likes":           likes(user, comment.Fullname()),
+			"gilded":          0,
+			"ups":             comment.Score,
+			"downs":           0,
+			"score":           comment.Score,
+			"edited":          editedTime(comment.Edited),
+			"created_utc":     float64(comment.Created.Unix()),
+			"depth":           depth,
+			"replies":         replies,
+		},
+	}
+}
+
+// subredditFullname returns the fullname of a subreddit. s.mu must be
+// held.
+func (s *Server) subredditFullname(name string) string {
+	if sub, ok := s.subreddits[strings.ToLower(name)]; ok {
+		return "t5_" + sub.ID
+	}
+	return ""
+}
+
+// authorFullname returns the fullname of a user. s.mu must be held.
+func (s *Server) authorFullname(username string) string {
+	if user, ok := s.users[strings.ToLower(username)]; ok {
+		return "t2_" + user.ID
+	}
+	return ""
+}
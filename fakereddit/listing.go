@@ -0,0 +1,159 @@
+package fakereddit
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+// default and maximum listing page sizes
+const (
+	defaultListingLimit = 25
+	maxListingLimit     = 100
+)
+
+// handleSubredditListing returns a handler listing the posts in one
+// or more subreddits (joined with "+") with the given sort. Removed
+// posts and those hidden by the user are left out. Sorts other than new and top are approximated.
+func (s *Server) handleSubredditListing(sortBy string) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		subreddits, ok := s.subredditsFromParam(params[0])
+		if !ok {
+			writeStatus(w, http.StatusNotFound)
+			return
+		}
+
+		var posts []*Post
+		for _, post := range s.posts {
+			if subreddits[strings.ToLower(post.Subreddit)] && !post.Removed && !isHidden(user, post.Fullname()) {
+				posts = append(posts, post)
+			}
+		}
+
+		// newest first, then by score for the score-based sorts
+		sort.Slice(posts, func(i, j int) bool {
+			return idLess(posts[j].ID, posts[i].ID)
+		})
+		if sortBy == reddit.SortTop || sortBy == reddit.SortHot {
+			sort.SliceStable(posts, func(i, j int) bool {
+				return posts[i].Score > posts[j].Score
+			})
+		}
+
+		things := make([]interface{}, len(posts))
+		names := make([]string, len(posts))
+		for i, post := range posts {
+			things[i] = s.postThing(post, user)
+			names[i] = post.Fullname()
+		}
+		writeJSON(w, paginate(r, things, names))
+	}
+}
+
+// subredditsFromParam looks up the subreddits joined with "+" in a
+// path parameter, returning them as a set of lowercase names. s.mu
+// must be held.
+func (s *Server) subredditsFromParam(param string) (map[string]bool, bool) {
+	subreddits := map[string]bool{}
+	for _, name := range strings.Split(param, "+") {
+		if _, ok := s.subreddits[strings.ToLower(name)]; !ok {
+			return nil, false
+		}
+		subreddits[strings.ToLower(name)] = true
+	}
+	return subreddits, true
+}
+
+// handleSubredditComments lists the newest comments in one or more
+// subreddits, without their replies
+func (s *Server) handleSubredditComments(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	subreddits, ok := s.subredditsFromParam(params[0])
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+
+	var comments []*Comment
+	for _, comment := range s.comments {
+		post := s.posts[comment.PostID]
+		if subreddits[strings.ToLower(post.Subreddit)] && !comment.Removed {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return idLess(comments[j].ID, comments[i].ID)
+	})
+
+	things := make([]interface{}, len(comments))
+	names := make([]string, len(comments))
+	for i, comment := range comments {
+		thing := s.commentThing(comment, 0, user)
+		thing["data"].(map[string]interface{})["replies"] = ""
+		things[i] = thing
+		names[i] = comment.Fullname()
+	}
+	writeJSON(w, paginate(r, things, names))
+}
+
+// paginate returns the page of a listing selected by the limit, after
+// and before parameters of the request. names holds the fullname of
+// each thing.
+func paginate(r *http.Request, things []interface{}, names []string) map[string]interface{} {
+	limit, err := strconv.Atoi(r.Form.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultListingLimit
+	}
+	if limit > maxListingLimit {
+		limit = maxListingLimit
+	}
+
+	start, end := 0, len(things)
+	if after := r.Form.Get("after"); after != "" {
+		start = indexOf(names, after) + 1
+		if start == 0 {
+			// unknown cursor, so nothing follows it
+			start = len(things)
+		}
+		end = start + limit
+	} else if before := r.Form.Get("before"); before != "" {
+		end = indexOf(names, before)
+		if end < 0 {
+			end = 0
+		}
+		start = end - limit
+	} else {
+		end = limit
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(things) {
+		end = len(things)
+	}
+	if start > end {
+		start = end
+	}
+
+	page := listing(things[start:end])
+	data := page["data"].(map[string]interface{})
+	if end < len(things) && end > start {
+		data["after"] = names[end-1]
+	}
+	if start > 0 && end > start {
+		data["before"] = names[start]
+	}
+	return page
+}
+
+// indexOf returns the index of name in names, or -1
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
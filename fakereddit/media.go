@@ -0,0 +1,228 @@
+package fakereddit
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// mediaUploadPath is where media is uploaded on the RedditServer,
+// standing in for reddit's S3 bucket
+const mediaUploadPath = "/media-upload"
+
+// maximum size of an uploaded file
+const maxMediaSize = 32 << 20
+
+// limits on galleries
+const (
+	minGalleryItems = 2
+	maxGalleryItems = 20
+)
+
+// handleMediaAsset leases an upload slot for a media file
+func (s *Server) handleMediaAsset(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	filename := path.Base(r.Form.Get("filepath"))
+	mimeType := r.Form.Get("mimetype")
+	if filename == "" || filename == "." || filename == "/" {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	if !strings.HasPrefix(mimeType, "image/") && !strings.HasPrefix(mimeType, "video/") {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+
+	asset := &MediaAsset{
+		ID:       s.newID(),
+		Filename: filename,
+		MimeType: mimeType,
+	}
+	s.media[asset.ID] = asset
+
+	// the action is protocol-relative, like reddit's
+	host := strings.TrimPrefix(strings.TrimPrefix(s.RedditServer.URL, "http://"), "https://")
+	writeJSON(w, map[string]interface{}{
+		"args": map[string]interface{}{
+			"action": "//" + host + mediaUploadPath,
+			"fields": []map[string]string{
+				{"name": "key", "value": mediaKey(asset)},
+				{"name": "Content-Type", "value": mimeType},
+				{"name": "success_action_status", "value": "201"},
+			},
+		},
+		"asset": map[string]interface{}{
+			"asset_id":         asset.ID,
+			"processing_state": "incomplete",
+			"payload": map[string]string{
+				"filepath": filename,
+			},
+			"websocket_url": "wss://ws.example.invalid/" + asset.ID,
+		},
+	})
+}
+
+// mediaKey returns the storage key of a media asset
+func mediaKey(asset *MediaAsset) string {
+	return "media/" + asset.ID + "/" + asset.Filename
+}
+
+// s3PostResponse is the XML returned after a successful upload
+type s3PostResponse struct {
+	XMLName  xml.Name `xml:"PostResponse"`
+	Location string   `xml:"Location"`
+	Key      string   `xml:"Key"`
+}
+
+// handleMediaUpload stores a file uploaded to a leased slot
+func (s *Server) handleMediaUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeStatus(w, http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseMultipartForm(maxMediaSize); err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the key identifies the lease
+	key := r.FormValue("key")
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+	asset, ok := s.media[parts[1]]
+	if !ok || mediaKey(asset) != key {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+	asset.Data = data
+	asset.URL = s.RedditServer.URL + mediaUploadPath + "/" + key
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusCreated)
+	xml.NewEncoder(w).Encode(s3PostResponse{
+		Location: s.RedditServer.URL + mediaUploadPath + "/" + url.PathEscape(key),
+		Key:      key,
+	})
+}
+
+// galleryRequest is the JSON body of a gallery submission
+type galleryRequest struct {
+	Subreddit     string `json:"sr"`
+	Title         string `json:"title"`
+	NSFW          bool   `json:"nsfw"`
+	Spoiler       bool   `json:"spoiler"`
+	SendReplies   *bool  `json:"sendreplies"`
+	FlairID       string `json:"flair_id"`
+	FlairText     string `json:"flair_text"`
+	CollectionID  string `json:"collection_id"`
+	EventStart    string `json:"event_start"`
+	EventEnd      string `json:"event_end"`
+	EventTimezone string `json:"event_tz"`
+	Items         []struct {
+		MediaID     string `json:"media_id"`
+		Caption     string `json:"caption"`
+		OutboundURL string `json:"outbound_url"`
+	} `json:"items"`
+}
+
+func (s *Server) handleSubmitGallery(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	var request galleryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	sub, errs := s.checkSubmission(request.Subreddit, request.Title)
+	if errs != nil {
+		writeJSONErrors(w, errs)
+		return
+	}
+	// the options are checked the same way as for other submissions
+	form := url.Values{
+		"nsfw":          {strconv.FormatBool(request.NSFW)},
+		"spoiler":       {strconv.FormatBool(request.Spoiler)},
+		"flair_id":      {request.FlairID},
+		"flair_text":    {request.FlairText},
+		"collection_id": {request.CollectionID},
+		"event_start":   {request.EventStart},
+		"event_end":     {request.EventEnd},
+		"event_tz":      {request.EventTimezone},
+	}
+	if request.SendReplies != nil {
+		form.Set("sendreplies", strconv.FormatBool(*request.SendReplies))
+	}
+	opts, errs := parseSubmitOptions(form)
+	if errs != nil {
+		writeJSONErrors(w, errs)
+		return
+	}
+	if len(request.Items) < minGalleryItems || len(request.Items) > maxGalleryItems {
+		writeJSONErrors(w, []string{"INVALID_OPTION", "galleries need between 2 and 20 items", "items"})
+		return
+	}
+
+	var gallery []GalleryItem
+	for _, item := range request.Items {
+		asset, ok := s.media[item.MediaID]
+		if !ok || asset.URL == "" {
+			writeJSONErrors(w, []string{"MISSING_MEDIA", "that media has not been uploaded", "items"})
+			return
+		}
+		gallery = append(gallery, GalleryItem{
+			MediaID:     item.MediaID,
+			Caption:     item.Caption,
+			OutboundURL: item.OutboundURL,
+		})
+	}
+
+	post := s.addPost(sub.Name, user.Name, request.Title)
+	post.Gallery = gallery
+	post.URL = "https://www.reddit.com/gallery/" + post.ID
+	opts.apply(post)
+
+	writeJSONData(w, map[string]interface{}{
+		"url":  post.URL,
+		"id":   post.ID,
+		"name": post.Fullname(),
+	})
+}
+
+// galleryData returns the gallery_data of a post, or nil if it is not
+// a gallery
+func galleryData(post *Post) interface{} {
+	if len(post.Gallery) == 0 {
+		return nil
+	}
+	var items []map[string]interface{}
+	for i, item := range post.Gallery {
+		items = append(items, map[string]interface{}{
+			"media_id":     item.MediaID,
+			"id":           i + 1,
+			"caption":      item.Caption,
+			"outbound_url": item.OutboundURL,
+		})
+	}
+	return map[string]interface{}{
+		"items": items,
+	}
+}
@@ -0,0 +1,372 @@
+package fakereddit
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+// modThing looks up the post or comment with the given fullname and
+// checks that the user moderates its subreddit, writing an error
+// response and returning false if not. Only one of the returned post
+// and comment is set. s.mu must be held.
+func (s *Server) modThing(w http.ResponseWriter, user *User, name string) (*Post, *Comment, bool) {
+	var (
+		post    *Post
+		comment *Comment
+	)
+	kind, id := splitFullname(name)
+	switch kind {
+	case "t3":
+		post = s.posts[id]
+	case "t1":
+		comment = s.comments[id]
+	}
+
+	var subreddit string
+	switch {
+	case post != nil:
+		subreddit = post.Subreddit
+	case comment != nil:
+		subreddit = s.posts[comment.PostID].Subreddit
+	default:
+		writeStatus(w, http.StatusNotFound)
+		return nil, nil, false
+	}
+	if !s.isModerator(user, subreddit) {
+		writeStatus(w, http.StatusForbidden)
+		return nil, nil, false
+	}
+	return post, comment, true
+}
+
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	post, comment, ok := s.modThing(w, user, r.Form.Get("id"))
+	if !ok {
+		return
+	}
+	if post != nil {
+		post.Removed, post.Spam, post.Approved = false, false, true
+		s.logModAction(user, reddit.ModActionApproveLink, post, nil, "")
+	} else {
+		comment.Removed, comment.Spam, comment.Approved = false, false, true
+		s.logModAction(user, reddit.ModActionApproveComment, nil, comment, "")
+	}
+	writeJSON(w, map[string]interface{}{})
+}
+
+// handleIgnoreReports returns a handler that sets whether reports on a
+// post or comment are ignored
+func (s *Server) handleIgnoreReports(ignore bool) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		post, comment, ok := s.modThing(w, user, r.Form.Get("id"))
+		if !ok {
+			return
+		}
+		if post != nil {
+			post.IgnoreReports = ignore
+		} else {
+			comment.IgnoreReports = ignore
+		}
+		action := reddit.ModActionIgnoreReports
+		if !ignore {
+			action = reddit.ModActionUnignoreReports
+		}
+		s.logModAction(user, action, post, comment, "")
+		writeJSON(w, map[string]interface{}{})
+	}
+}
+
+// handleLock returns a handler that locks or unlocks a post or comment
+func (s *Server) handleLock(lock bool) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		post, comment, ok := s.modThing(w, user, r.Form.Get("id"))
+		if !ok {
+			return
+		}
+		if post != nil {
+			post.Locked = lock
+		} else {
+			comment.Locked = lock
+		}
+		action := reddit.ModActionLock
+		if !lock {
+			action = reddit.ModActionUnlock
+		}
+		s.logModAction(user, action, post, comment, "")
+		writeJSON(w, map[string]interface{}{})
+	}
+}
+
+// handleNSFW returns a handler that marks or unmarks a post as NSFW
+func (s *Server) handleNSFW(nsfw bool) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		post, _, ok := s.modThing(w, user, r.Form.Get("id"))
+		if !ok {
+			return
+		}
+		if post == nil {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+		post.NSFW = nsfw
+		// reddit only logs marking
+		if nsfw {
+			s.logModAction(user, reddit.ModActionMarkNSFW, post, nil, "")
+		}
+		writeJSON(w, map[string]interface{}{})
+	}
+}
+
+// handleSpoiler returns a handler that marks or unmarks a post as a
+// spoiler
+func (s *Server) handleSpoiler(spoiler bool) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		post, _, ok := s.modThing(w, user, r.Form.Get("id"))
+		if !ok {
+			return
+		}
+		if post == nil {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+		post.Spoiler = spoiler
+		action := reddit.ModActionSpoiler
+		if !spoiler {
+			action = reddit.ModActionUnspoiler
+		}
+		s.logModAction(user, action, post, nil, "")
+		writeJSON(w, map[string]interface{}{})
+	}
+}
+
+func (s *Server) handleDistinguish(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	post, comment, ok := s.modThing(w, user, r.Form.Get("id"))
+	if !ok {
+		return
+	}
+
+	var distinguished string
+	switch how := r.Form.Get("how"); how {
+	case "yes":
+		distinguished = "moderator"
+	case "no":
+	case "admin", "special":
+		// only admins can use these
+		writeStatus(w, http.StatusForbidden)
+		return
+	default:
+		writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "how"})
+		return
+	}
+	sticky := formBool(r, "sticky")
+
+	var thing map[string]interface{}
+	if post != nil {
+		if sticky {
+			writeJSONErrors(w, []string{"INVALID_OPTION", "only comments can be stickied", "sticky"})
+			return
+		}
+		post.Distinguished = distinguished
+		thing = s.postThing(post, user)
+	} else {
+		if sticky && (!strings.HasPrefix(comment.ParentID, "t3_") || distinguished == "") {
+			writeJSONErrors(w, []string{"INVALID_OPTION", "only distinguished top-level comments can be stickied", "sticky"})
+			return
+		}
+		if sticky {
+			// only one comment can be stickied on a post
+			for _, c := range s.comments {
+				if c.PostID == comment.PostID {
+					c.Stickied = false
+				}
+			}
+		}
+		comment.Distinguished = distinguished
+		comment.Stickied = sticky
+		thing = s.commentThing(comment, 0, user)
+		thing["data"].(map[string]interface{})["replies"] = ""
+	}
+
+	s.logModAction(user, reddit.ModActionDistinguish, post, comment, "")
+
+	writeJSONData(w, map[string]interface{}{
+		"things": []interface{}{thing},
+	})
+}
+
+func (s *Server) handleSuggestedSort(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	post, _, ok := s.modThing(w, user, r.Form.Get("id"))
+	if !ok {
+		return
+	}
+	if post == nil {
+		writeJSONErrors(w, []string{"NO_THING_ID", "no post found", "id"})
+		return
+	}
+
+	switch suggested := r.Form.Get("sort"); suggested {
+	case "confidence", "top", "new", "controversial", "old", "random", "qa", "live":
+		post.SuggestedSort = suggested
+	case "blank", "":
+		post.SuggestedSort = ""
+	default:
+		writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "sort"})
+		return
+	}
+	s.logModAction(user, reddit.ModActionSetSuggestedSort, post, nil, "")
+	writeJSONErrors(w)
+}
+
+// removalReasonsRequest is the JSON form field recording removal
+// reasons
+type removalReasonsRequest struct {
+	ItemIDs  []string `json:"item_ids"`
+	ReasonID string   `json:"reason_id"`
+	ModNote  string   `json:"mod_note"`
+}
+
+func (s *Server) handleRemovalReasons(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	var request removalReasonsRequest
+	if err := json.Unmarshal([]byte(r.PostFormValue("json")), &request); err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+
+	// check every item before changing any
+	for _, name := range request.ItemIDs {
+		post, comment, ok := s.modThing(w, user, name)
+		if !ok {
+			return
+		}
+		if (post != nil && !post.Removed) || (comment != nil && !comment.Removed) {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+	}
+	for _, name := range request.ItemIDs {
+		kind, id := splitFullname(name)
+		if kind == "t3" {
+			s.posts[id].RemovalReason = request.ReasonID
+			s.posts[id].ModNote = request.ModNote
+		} else {
+			s.comments[id].RemovalReason = request.ReasonID
+			s.comments[id].ModNote = request.ModNote
+		}
+	}
+	writeJSON(w, map[string]interface{}{})
+}
+
+// reports returns the user_reports, mod_reports and num_reports of a
+// post or comment in the subreddit as seen by the user. Reports made
+// by moderators are mod reports, and only moderators can see any of
+// them. s.mu must be held.
+func (s *Server) reports(reports []Report, subreddit string, user *User) ([]interface{}, []interface{}, interface{}) {
+	userReports := []interface{}{}
+	modReports := []interface{}{}
+	if !s.isModerator(user, subreddit) {
+		return userReports, modReports, nil
+	}
+
+	// user reports are counted by reason, in the order first given
+	counts := map[string]int{}
+	var reasons []string
+	for _, report := range reports {
+		if s.isModerator(s.users[strings.ToLower(report.User)], subreddit) {
+			modReports = append(modReports, []interface{}{report.Reason, report.User})
+			continue
+		}
+		if counts[report.Reason] == 0 {
+			reasons = append(reasons, report.Reason)
+		}
+		counts[report.Reason]++
+	}
+	for _, reason := range reasons {
+		userReports = append(userReports, []interface{}{reason, counts[reason], false, true})
+	}
+	return userReports, modReports, len(reports)
+}
+
+// inModListing reports whether a post or comment belongs in a
+// moderation listing. Only posts are listed as unmoderated.
+func inModListing(location string, removed, approved, ignoreReports bool, reports []Report, edited time.Time) bool {
+	reported := len(reports) > 0 && !removed && !ignoreReports
+	switch location {
+	case reddit.ModQueue:
+		return reported && !approved
+	case reddit.ModReports:
+		return reported
+	case reddit.ModSpam:
+		return removed
+	case reddit.ModEdited:
+		return !edited.IsZero()
+	case reddit.ModUnmoderated:
+		return !approved && !removed
+	}
+	return false
+}
+
+// handleModListing returns a handler listing the posts and comments in
+// one of a subreddit's moderation listings, newest first. Only
+// moderators can see them.
+func (s *Server) handleModListing(location string) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		subreddits, ok := s.subredditsFromParam(params[0])
+		if !ok {
+			writeStatus(w, http.StatusNotFound)
+			return
+		}
+		for sub := range subreddits {
+			if !s.isModerator(user, sub) {
+				writeStatus(w, http.StatusForbidden)
+				return
+			}
+		}
+		only := r.Form.Get("only")
+
+		type item struct {
+			id    string
+			name  string
+			thing map[string]interface{}
+		}
+		var items []item
+		if only != "comments" {
+			for _, post := range s.posts {
+				if !subreddits[strings.ToLower(post.Subreddit)] || post.Deleted {
+					continue
+				}
+				if inModListing(location, post.Removed, post.Approved, post.IgnoreReports, post.Reports, post.Edited) {
+					items = append(items, item{post.ID, post.Fullname(), s.postThing(post, user)})
+				}
+			}
+		}
+		if only != "links" && location != reddit.ModUnmoderated {
+			for _, comment := range s.comments {
+				post := s.posts[comment.PostID]
+				if !subreddits[strings.ToLower(post.Subreddit)] || comment.Deleted {
+					continue
+				}
+				if inModListing(location, comment.Removed, comment.Approved, comment.IgnoreReports, comment.Reports, comment.Edited) {
+					thing := s.commentThing(comment, 0, user)
+					thing["data"].(map[string]interface{})["replies"] = ""
+					items = append(items, item{comment.ID, comment.Fullname(), thing})
+				}
+			}
+		}
+		sort.Slice(items, func(i, j int) bool {
+			return idLess(items[j].id, items[i].id)
+		})
+
+		things := make([]interface{}, len(items))
+		names := make([]string, len(items))
+		for i, item := range items {
+			things[i] = item.thing
+			names[i] = item.name
+		}
+		writeJSON(w, paginate(r, things, names))
+	}
+}
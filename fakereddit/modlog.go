@@ -0,0 +1,130 @@
+package fakereddit
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// logModAction records a moderator's action on a post or comment in
+// its subreddit's moderation log. Only one of post and comment is set.
+// s.mu must be held.
+func (s *Server) logModAction(user *User, action string, post *Post, comment *Comment, details string) {
+	entry := ModAction{
+		ID:        "ModAction_" + s.newID(),
+		Action:    action,
+		Moderator: user.Name,
+		Details:   details,
+		Created:   time.Now(),
+	}
+	if comment != nil {
+		entry.TargetFullname = comment.Fullname()
+		entry.TargetAuthor = comment.Author
+		post = s.posts[comment.PostID]
+	} else {
+		entry.TargetFullname = post.Fullname()
+		entry.TargetAuthor = post.Author
+	}
+
+	sub := s.subreddits[strings.ToLower(post.Subreddit)]
+	sub.ModLog = append(sub.ModLog, entry)
+}
+
+// logUserModAction records a moderator's action on a user in the
+// subreddit's moderation log. s.mu must be held.
+func (s *Server) logUserModAction(sub *Subreddit, user *User, action string, target *User, details string) {
+	sub.ModLog = append(sub.ModLog, ModAction{
+		ID:             "ModAction_" + s.newID(),
+		Action:         action,
+		Moderator:      user.Name,
+		TargetFullname: "t2_" + target.ID,
+		TargetAuthor:   target.Name,
+		Details:        details,
+		Created:        time.Now(),
+	})
+}
+
+// handleModLog lists the entries in a subreddit's moderation log,
+// newest first, filtered by the type and mod parameters. Only
+// moderators can see it.
+func (s *Server) handleModLog(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.subreddits[strings.ToLower(params[0])]
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	if !s.isModerator(user, sub.Name) {
+		writeStatus(w, http.StatusForbidden)
+		return
+	}
+	action := r.Form.Get("type")
+	mods := map[string]bool{}
+	if mod := r.Form.Get("mod"); mod != "" {
+		for _, name := range strings.Split(mod, ",") {
+			mods[strings.ToLower(name)] = true
+		}
+	}
+
+	var things []interface{}
+	var names []string
+	for i := len(sub.ModLog) - 1; i >= 0; i-- {
+		entry := sub.ModLog[i]
+		if action != "" && entry.Action != action {
+			continue
+		}
+		if len(mods) > 0 && !mods[strings.ToLower(entry.Moderator)] {
+			continue
+		}
+		things = append(things, s.modActionThing(sub, entry))
+		names = append(names, entry.ID)
+	}
+	writeJSON(w, paginate(r, things, names))
+}
+
+// modActionThing returns the JSON representation of a moderation log
+// entry. s.mu must be held.
+func (s *Server) modActionThing(sub *Subreddit, entry ModAction) map[string]interface{} {
+	var modID string
+	if mod, ok := s.users[strings.ToLower(entry.Moderator)]; ok {
+		modID = mod.ID
+	}
+
+	// describe the target as reddit does
+	var title, permalink, body interface{}
+	kind, id := splitFullname(entry.TargetFullname)
+	switch kind {
+	case "t3":
+		if post, ok := s.posts[id]; ok {
+			title = post.Title
+			permalink = post.permalink()
+			body = post.Body
+		}
+	case "t1":
+		if comment, ok := s.comments[id]; ok {
+			post := s.posts[comment.PostID]
+			title = post.Title
+			permalink = post.permalink() + comment.ID + "/"
+			body = comment.Body
+		}
+	}
+
+	return map[string]interface{}{
+		"kind": "modaction",
+		"data": map[string]interface{}{
+			"id":               entry.ID,
+			"action":           entry.Action,
+			"mod":              entry.Moderator,
+			"mod_id36":         modID,
+			"subreddit":        sub.Name,
+			"sr_id36":          sub.ID,
+			"target_fullname":  nullString(entry.TargetFullname),
+			"target_author":    nullString(entry.TargetAuthor),
+			"target_title":     title,
+			"target_permalink": permalink,
+			"target_body":      body,
+			"details":          nullString(entry.Details),
+			"description":      nil,
+			"created_utc":      float64(entry.Created.Unix()),
+		},
+	}
+}
@@ -0,0 +1,532 @@
+package fakereddit
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+// default and maximum number of conversations in a page of modmail
+const (
+	defaultModmailLimit = 25
+	maxModmailLimit     = 100
+)
+
+// copy returns a deep copy of the conversation
+func (c Conversation) copy() Conversation {
+	c.Messages = append([]ConversationMessage(nil), c.Messages...)
+	c.Actions = append([]ConversationAction(nil), c.Actions...)
+	read := map[string]bool{}
+	for name := range c.Read {
+		read[name] = true
+	}
+	c.Read = read
+	return c
+}
+
+// internal reports whether the conversation is a discussion between
+// moderators
+func (c *Conversation) internal() bool {
+	return c.Participant == ""
+}
+
+// folder returns the modmail folder the conversation is listed in
+func (c *Conversation) folder() string {
+	switch {
+	case c.State == reddit.ModmailStateArchived:
+		return reddit.ModmailArchived
+	case c.Auto:
+		return reddit.ModmailNotifications
+	case c.internal():
+		return reddit.ModmailMod
+	case c.State == reddit.ModmailStateInProgress:
+		return reddit.ModmailInProgress
+	}
+	return reddit.ModmailNew
+}
+
+// lastID returns the ID of the latest message or action in the
+// conversation. IDs are increasing, so this orders conversations by
+// when they were last updated.
+func (c *Conversation) lastID() string {
+	var last string
+	for _, message := range c.Messages {
+		if idLess(last, message.ID) {
+			last = message.ID
+		}
+	}
+	for _, action := range c.Actions {
+		if idLess(last, action.ID) {
+			last = action.ID
+		}
+	}
+	return last
+}
+
+// lastMessageID returns the ID of the latest message written by a
+// moderator if mod is set, or by anyone else if not. s.mu must be
+// held.
+func (s *Server) lastMessageID(sub *Subreddit, conv *Conversation, mod bool) string {
+	var last string
+	for _, message := range conv.Messages {
+		if s.isModerator(s.users[strings.ToLower(message.Author)], sub.Name) == mod {
+			last = message.ID
+		}
+	}
+	return last
+}
+
+// addConversation starts a modmail conversation with the subreddit.
+// Conversations started by moderators are discussions between them.
+// s.mu must be held.
+func (s *Server) addConversation(sub *Subreddit, author, subject, body string) *Conversation {
+	conv := Conversation{
+		ID:      s.newID(),
+		Subject: subject,
+		State:   reddit.ModmailStateNew,
+	}
+	if !s.isModerator(s.users[strings.ToLower(author)], sub.Name) {
+		conv.Participant = author
+	}
+	sub.Conversations = append(sub.Conversations, conv)
+	c := &sub.Conversations[len(sub.Conversations)-1]
+	s.addConversationMessage(c, author, body, c.internal())
+	return c
+}
+
+// addConversationMessage adds a message to a conversation, leaving it
+// unread by everyone but the author. s.mu must be held.
+func (s *Server) addConversationMessage(conv *Conversation, author, body string, internal bool) {
+	conv.Messages = append(conv.Messages, ConversationMessage{
+		ID:       s.newID(),
+		Author:   author,
+		Body:     body,
+		Internal: internal,
+		Created:  time.Now(),
+	})
+	conv.Read = map[string]bool{strings.ToLower(author): true}
+}
+
+// addConversationAction records a moderator action on a conversation.
+// s.mu must be held.
+func (s *Server) addConversationAction(conv *Conversation, user *User, action int) {
+	conv.Actions = append(conv.Actions, ConversationAction{
+		ID:        s.newID(),
+		Type:      action,
+		Moderator: user.Name,
+		Created:   time.Now(),
+	})
+}
+
+// findConversation looks up a conversation and checks that the user
+// moderates its subreddit, writing an error response and returning
+// false if not. s.mu must be held.
+func (s *Server) findConversation(w http.ResponseWriter, user *User, id string) (*Subreddit, *Conversation, bool) {
+	for _, sub := range s.subreddits {
+		for i := range sub.Conversations {
+			if sub.Conversations[i].ID != id {
+				continue
+			}
+			if !s.isModerator(user, sub.Name) {
+				writeStatus(w, http.StatusForbidden)
+				return nil, nil, false
+			}
+			return sub, &sub.Conversations[i], true
+		}
+	}
+	writeStatus(w, http.StatusNotFound)
+	return nil, nil, false
+}
+
+// handleModmail lists the conversations in a modmail folder across
+// the subreddits in the entity parameter, or every subreddit the user
+// moderates
+func (s *Server) handleModmail(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	var subreddits []*Subreddit
+	if entity := r.Form.Get("entity"); entity != "" {
+		for _, name := range strings.Split(entity, ",") {
+			sub, ok := s.modSubreddit(w, user, name)
+			if !ok {
+				return
+			}
+			subreddits = append(subreddits, sub)
+		}
+	} else {
+		for _, sub := range s.subreddits {
+			if s.isModerator(user, sub.Name) {
+				subreddits = append(subreddits, sub)
+			}
+		}
+	}
+
+	state := r.Form.Get("state")
+	if state == "" {
+		state = reddit.ModmailAll
+	}
+	limit := defaultModmailLimit
+	if l := r.Form.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+		if n < maxModmailLimit {
+			limit = n
+		} else {
+			limit = maxModmailLimit
+		}
+	}
+
+	type item struct {
+		sub  *Subreddit
+		conv *Conversation
+		key  string
+	}
+	var items []item
+	for _, sub := range subreddits {
+		for i := range sub.Conversations {
+			conv := &sub.Conversations[i]
+			switch state {
+			case reddit.ModmailAll:
+			case reddit.ModmailHighlighted:
+				if !conv.Highlighted {
+					continue
+				}
+			default:
+				if conv.folder() != state {
+					continue
+				}
+			}
+			items = append(items, item{sub, conv, conv.lastID()})
+		}
+	}
+
+	// sort newest first by the chosen update
+	sortBy := r.Form.Get("sort")
+	for i := range items {
+		switch sortBy {
+		case reddit.ModmailSortMod:
+			items[i].key = s.lastMessageID(items[i].sub, items[i].conv, true)
+		case reddit.ModmailSortUser:
+			items[i].key = s.lastMessageID(items[i].sub, items[i].conv, false)
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if sortBy == reddit.ModmailSortUnread {
+			iUnread := !items[i].conv.Read[strings.ToLower(user.Name)]
+			jUnread := !items[j].conv.Read[strings.ToLower(user.Name)]
+			if iUnread != jUnread {
+				return iUnread
+			}
+		}
+		if items[i].key != items[j].key {
+			return idLess(items[j].key, items[i].key)
+		}
+		return idLess(items[j].conv.ID, items[i].conv.ID)
+	})
+
+	// start after the given conversation
+	if after := r.Form.Get("after"); after != "" {
+		for i, item := range items {
+			if item.conv.ID == after {
+				items = items[i+1:]
+				break
+			}
+		}
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	conversations := map[string]interface{}{}
+	messages := map[string]interface{}{}
+	ids := []string{}
+	for _, item := range items {
+		conversations[item.conv.ID] = s.conversationData(item.sub, item.conv, user)
+		if n := len(item.conv.Messages); n > 0 {
+			message := item.conv.Messages[n-1]
+			messages[message.ID] = s.conversationMessageData(item.sub, item.conv, message)
+		}
+		ids = append(ids, item.conv.ID)
+	}
+	writeJSON(w, map[string]interface{}{
+		"conversations":   conversations,
+		"conversationIds": ids,
+		"messages":        messages,
+		"viewerId":        "t2_" + user.ID,
+	})
+}
+
+func (s *Server) handleModmailThread(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, conv, ok := s.findConversation(w, user, params[0])
+	if !ok {
+		return
+	}
+	if formBool(r, "markRead") {
+		conv.Read[strings.ToLower(user.Name)] = true
+	}
+	s.writeConversation(w, sub, conv, user)
+}
+
+func (s *Server) handleModmailReply(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, conv, ok := s.findConversation(w, user, params[0])
+	if !ok {
+		return
+	}
+	body := r.Form.Get("body")
+	if strings.TrimSpace(body) == "" {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+
+	// discussions between moderators are always internal
+	internal := formBool(r, "isInternal") || conv.internal()
+	s.addConversationMessage(conv, user.Name, body, internal)
+
+	// public replies are sent to the user, as on reddit
+	if !internal {
+		if conv.State == reddit.ModmailStateNew {
+			conv.State = reddit.ModmailStateInProgress
+		}
+		if participant, ok := s.users[strings.ToLower(conv.Participant)]; ok {
+			participant.Inbox = append(participant.Inbox, Message{
+				ID:      s.newID(),
+				From:    "/r/" + sub.Name,
+				To:      participant.Name,
+				Subject: "re: " + conv.Subject,
+				Body:    body,
+				Created: time.Now(),
+			})
+		}
+	}
+	s.writeConversation(w, sub, conv, user)
+}
+
+// handleModmailAction returns a handler for one of the actions on a
+// conversation
+func (s *Server) handleModmailAction(action int) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		sub, conv, ok := s.findConversation(w, user, params[0])
+		if !ok {
+			return
+		}
+
+		switch action {
+		case reddit.ModmailActionArchive:
+			conv.State = reddit.ModmailStateArchived
+		case reddit.ModmailActionUnarchive:
+			if conv.State != reddit.ModmailStateArchived {
+				writeStatus(w, http.StatusBadRequest)
+				return
+			}
+			conv.State = reddit.ModmailStateInProgress
+		case reddit.ModmailActionHighlight:
+			conv.Highlighted = true
+		case reddit.ModmailActionUnhighlight:
+			conv.Highlighted = false
+		case reddit.ModmailActionMute, reddit.ModmailActionUnmute:
+			target, ok := s.users[strings.ToLower(conv.Participant)]
+			if !ok || s.isModerator(target, sub.Name) {
+				writeStatus(w, http.StatusBadRequest)
+				return
+			}
+			if action == reddit.ModmailActionUnmute {
+				if !removeRelationship(&sub.Muted, target.Name) {
+					writeStatus(w, http.StatusBadRequest)
+					return
+				}
+				s.logUserModAction(sub, user, reddit.ModActionUnmuteUser, target, "")
+				break
+			}
+
+			hours, _ := strconv.Atoi(r.Form.Get("num_hours"))
+			switch hours {
+			case reddit.ModmailMute3Days, reddit.ModmailMute7Days, reddit.ModmailMute28Days:
+			default:
+				writeStatus(w, http.StatusBadRequest)
+				return
+			}
+			entry := Relationship{
+				ID:   "rel_" + s.newID(),
+				Name: target.Name,
+				Date: time.Now(),
+			}
+			entry.Expires = entry.Date.Add(time.Duration(hours) * time.Hour)
+			removeRelationship(&sub.Muted, target.Name)
+			sub.Muted = append(sub.Muted, entry)
+			s.logUserModAction(sub, user, reddit.ModActionMuteUser, target, fmt.Sprintf("%d days", hours/24))
+		}
+
+		s.addConversationAction(conv, user, action)
+		s.writeConversation(w, sub, conv, user)
+	}
+}
+
+// handleModmailMark returns a handler that marks the conversations in
+// the conversationIds parameter as read or unread
+func (s *Server) handleModmailMark(read bool) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		// check every conversation before changing any
+		var conversations []*Conversation
+		for _, id := range strings.Split(r.Form.Get("conversationIds"), ",") {
+			_, conv, ok := s.findConversation(w, user, id)
+			if !ok {
+				return
+			}
+			conversations = append(conversations, conv)
+		}
+		for _, conv := range conversations {
+			if read {
+				conv.Read[strings.ToLower(user.Name)] = true
+			} else {
+				delete(conv.Read, strings.ToLower(user.Name))
+			}
+		}
+		writeJSON(w, map[string]interface{}{})
+	}
+}
+
+/* JSON representations */
+
+// writeConversation writes a conversation with all of its messages
+// and actions. s.mu must be held.
+func (s *Server) writeConversation(w http.ResponseWriter, sub *Subreddit, conv *Conversation, user *User) {
+	messages := map[string]interface{}{}
+	for _, message := range conv.Messages {
+		messages[message.ID] = s.conversationMessageData(sub, conv, message)
+	}
+	actions := map[string]interface{}{}
+	for _, action := range conv.Actions {
+		actions[action.ID] = map[string]interface{}{
+			"id":           action.ID,
+			"actionTypeId": action.Type,
+			"author":       s.modmailAuthorData(sub, conv, action.Moderator),
+			"date":         modmailTime(action.Created),
+		}
+	}
+	writeJSON(w, map[string]interface{}{
+		"conversation": s.conversationData(sub, conv, user),
+		"messages":     messages,
+		"modActions":   actions,
+	})
+}
+
+// conversationData returns the JSON representation of a conversation
+// as seen by the user. s.mu must be held.
+func (s *Server) conversationData(sub *Subreddit, conv *Conversation, user *User) map[string]interface{} {
+	type obj struct {
+		id, key string
+	}
+	var objs []obj
+	var authors []interface{}
+	seen := map[string]bool{}
+	var lastUpdated, lastUserUpdate, lastModUpdate time.Time
+	for _, message := range conv.Messages {
+		objs = append(objs, obj{message.ID, "messages"})
+		if !seen[strings.ToLower(message.Author)] {
+			seen[strings.ToLower(message.Author)] = true
+			authors = append(authors, s.modmailAuthorData(sub, conv, message.Author))
+		}
+		lastUpdated = message.Created
+		if s.isModerator(s.users[strings.ToLower(message.Author)], sub.Name) {
+			lastModUpdate = message.Created
+		} else {
+			lastUserUpdate = message.Created
+		}
+	}
+	for _, action := range conv.Actions {
+		objs = append(objs, obj{action.ID, "modActions"})
+		if action.Created.After(lastUpdated) {
+			lastUpdated = action.Created
+		}
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return idLess(objs[i].id, objs[j].id)
+	})
+	objIDs := make([]interface{}, len(objs))
+	for i, o := range objs {
+		objIDs[i] = map[string]interface{}{"id": o.id, "key": o.key}
+	}
+
+	var lastUnread interface{}
+	if n := len(conv.Messages); n > 0 && !conv.Read[strings.ToLower(user.Name)] {
+		lastUnread = modmailTime(conv.Messages[n-1].Created)
+	}
+	participant := map[string]interface{}{}
+	if !conv.internal() {
+		participant = s.modmailAuthorData(sub, conv, conv.Participant)
+	}
+
+	return map[string]interface{}{
+		"id":            conv.ID,
+		"subject":       conv.Subject,
+		"state":         conv.State,
+		"isHighlighted": conv.Highlighted,
+		"isInternal":    conv.internal(),
+		"isAuto":        conv.Auto,
+		"isRepliable":   true,
+		"numMessages":   len(conv.Messages),
+		"owner": map[string]interface{}{
+			"id":          "t5_" + sub.ID,
+			"displayName": sub.Name,
+			"type":        "subreddit",
+		},
+		"participant":    participant,
+		"authors":        authors,
+		"objIds":         objIDs,
+		"lastUpdated":    modmailTime(lastUpdated),
+		"lastUserUpdate": modmailTime(lastUserUpdate),
+		"lastModUpdate":  modmailTime(lastModUpdate),
+		"lastUnread":     lastUnread,
+	}
+}
+
+// conversationMessageData returns the JSON representation of a
+// message in a conversation. s.mu must be held.
+func (s *Server) conversationMessageData(sub *Subreddit, conv *Conversation, message ConversationMessage) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           message.ID,
+		"body":         "<!-- SC_OFF --><div class=\"md\"><p>" + html.EscapeString(message.Body) + "</p>\n</div><!-- SC_ON -->",
+		"bodyMarkdown": message.Body,
+		"author":       s.modmailAuthorData(sub, conv, message.Author),
+		"date":         modmailTime(message.Created),
+		"isInternal":   message.Internal,
+	}
+}
+
+// modmailAuthorData returns the JSON representation of a user in a
+// conversation. s.mu must be held.
+func (s *Server) modmailAuthorData(sub *Subreddit, conv *Conversation, name string) map[string]interface{} {
+	var id int64
+	user, ok := s.users[strings.ToLower(name)]
+	if ok {
+		id, _ = strconv.ParseInt(user.ID, 36, 64)
+		name = user.Name
+	}
+	isOP := len(conv.Messages) > 0 && strings.EqualFold(conv.Messages[0].Author, name)
+	return map[string]interface{}{
+		"id":            id,
+		"name":          name,
+		"isMod":         s.isModerator(user, sub.Name),
+		"isAdmin":       false,
+		"isOp":          isOP,
+		"isParticipant": !conv.internal() && strings.EqualFold(conv.Participant, name),
+		"isHidden":      false,
+		"isDeleted":     !ok,
+	}
+}
+
+// modmailTime returns a time in the ISO 8601 format used by modmail,
+// or nil if it is unset
+func modmailTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,163 @@
+package fakereddit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// limits on polls
+const (
+	minPollOptions  = 2
+	maxPollOptions  = 6
+	minPollDuration = 1
+	maxPollDuration = 7
+)
+
+// pollRequest is the JSON body of a poll submission
+type pollRequest struct {
+	Subreddit     string   `json:"sr"`
+	Title         string   `json:"title"`
+	Text          string   `json:"text"`
+	Options       []string `json:"options"`
+	Duration      int      `json:"duration"`
+	NSFW          bool     `json:"nsfw"`
+	Spoiler       bool     `json:"spoiler"`
+	SendReplies   *bool    `json:"sendreplies"`
+	FlairID       string   `json:"flair_id"`
+	FlairText     string   `json:"flair_text"`
+	CollectionID  string   `json:"collection_id"`
+	EventStart    string   `json:"event_start"`
+	EventEnd      string   `json:"event_end"`
+	EventTimezone string   `json:"event_tz"`
+}
+
+func (s *Server) handleSubmitPoll(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	var request pollRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	sub, errs := s.checkSubmission(request.Subreddit, request.Title)
+	if errs != nil {
+		writeJSONErrors(w, errs)
+		return
+	}
+
+	// the options are checked the same way as for other submissions
+	form := url.Values{
+		"nsfw":          {strconv.FormatBool(request.NSFW)},
+		"spoiler":       {strconv.FormatBool(request.Spoiler)},
+		"flair_id":      {request.FlairID},
+		"flair_text":    {request.FlairText},
+		"collection_id": {request.CollectionID},
+		"event_start":   {request.EventStart},
+		"event_end":     {request.EventEnd},
+		"event_tz":      {request.EventTimezone},
+	}
+	if request.SendReplies != nil {
+		form.Set("sendreplies", strconv.FormatBool(*request.SendReplies))
+	}
+	opts, errs := parseSubmitOptions(form)
+	if errs != nil {
+		writeJSONErrors(w, errs)
+		return
+	}
+	if len(request.Options) < minPollOptions || len(request.Options) > maxPollOptions {
+		writeJSONErrors(w, []string{"INVALID_OPTION", "polls need between 2 and 6 options", "options"})
+		return
+	}
+	if request.Duration < minPollDuration || request.Duration > maxPollDuration {
+		writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "duration"})
+		return
+	}
+
+	poll := &Poll{
+		VotingEnd: time.Now().Add(time.Duration(request.Duration) * 24 * time.Hour),
+		Votes:     map[string]string{},
+	}
+	for _, text := range request.Options {
+		if strings.TrimSpace(text) == "" {
+			writeJSONErrors(w, []string{"NO_TEXT", "we need something here", "options"})
+			return
+		}
+		poll.Options = append(poll.Options, PollOption{
+			ID:   s.newID(),
+			Text: text,
+		})
+	}
+
+	post := s.addPost(sub.Name, user.Name, request.Title)
+	post.IsSelf = true
+	post.Body = request.Text
+	post.Poll = poll
+	opts.apply(post)
+
+	writeJSONData(w, map[string]interface{}{
+		"url":  "https://www.reddit.com" + post.permalink(),
+		"id":   post.ID,
+		"name": post.Fullname(),
+	})
+}
+
+// VotePoll records a user's vote in a poll post, returning false if
+// the post has no such option or voting has ended
+func (s *Server) VotePoll(postID, username, optionID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[postID]
+	if !ok || post.Poll == nil || time.Now().After(post.Poll.VotingEnd) {
+		return false
+	}
+	for _, option := range post.Poll.Options {
+		if option.ID == optionID {
+			post.Poll.Votes[strings.ToLower(username)] = optionID
+			return true
+		}
+	}
+	return false
+}
+
+// pollData returns the poll_data of a post as seen by the user, or nil
+// if it has no poll. As on reddit, vote counts are hidden until the
+// user has voted or voting has ended.
+func pollData(post *Post, user *User) interface{} {
+	if post.Poll == nil {
+		return nil
+	}
+
+	var selection interface{}
+	if user != nil {
+		if option, ok := post.Poll.Votes[strings.ToLower(user.Name)]; ok {
+			selection = option
+		}
+	}
+	showCounts := selection != nil || time.Now().After(post.Poll.VotingEnd)
+
+	counts := map[string]int{}
+	for _, option := range post.Poll.Votes {
+		counts[option]++
+	}
+	var options []map[string]interface{}
+	for _, option := range post.Poll.Options {
+		o := map[string]interface{}{
+			"id":   option.ID,
+			"text": option.Text,
+		}
+		if showCounts {
+			o["vote_count"] = counts[option.ID]
+		}
+		options = append(options, o)
+	}
+
+	return map[string]interface{}{
+		"options":              options,
+		"total_vote_count":     len(post.Poll.Votes),
+		"voting_end_timestamp": post.Poll.VotingEnd.UnixNano() / int64(time.Millisecond),
+		"user_selection":       selection,
+	}
+}
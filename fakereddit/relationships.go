@@ -0,0 +1,346 @@
+package fakereddit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+// limits on relationships
+const (
+	maxBanDuration  = 999
+	maxBanReasonLen = 100
+	maxBanNoteLen   = 300
+)
+
+// relationships returns the list for a relationship type, or nil if
+// it isn't one kept as a list
+func (sub *Subreddit) relationships(rel string) *[]Relationship {
+	switch rel {
+	case reddit.RelBanned:
+		return &sub.Banned
+	case reddit.RelMuted:
+		return &sub.Muted
+	case reddit.RelContributor:
+		return &sub.Contributors
+	case reddit.RelModeratorInvite:
+		return &sub.Invites
+	}
+	return nil
+}
+
+// removeRelationship removes a user from a list, returning false if
+// they weren't on it
+func removeRelationship(list *[]Relationship, username string) bool {
+	for i, r := range *list {
+		if strings.EqualFold(r.Name, username) {
+			*list = append((*list)[:i], (*list)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// findRelationship returns a user's entry in a list
+func findRelationship(list []Relationship, username string) (*Relationship, bool) {
+	for i := range list {
+		if strings.EqualFold(list[i].Name, username) {
+			return &list[i], true
+		}
+	}
+	return nil, false
+}
+
+// parsePermissions decodes a permission string like "-all,+posts",
+// returning nil for all permissions
+func parsePermissions(s string) []string {
+	var permissions []string
+	for _, p := range strings.Split(s, ",") {
+		switch {
+		case p == "+all":
+			return nil
+		case strings.HasPrefix(p, "+"):
+			permissions = append(permissions, p[1:])
+		}
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return permissions
+}
+
+// modSubreddit looks up the subreddit in the path and checks that the
+// user moderates it, writing an error response and returning false if
+// not. s.mu must be held.
+func (s *Server) modSubreddit(w http.ResponseWriter, user *User, name string) (*Subreddit, bool) {
+	sub, ok := s.subreddits[strings.ToLower(name)]
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return nil, false
+	}
+	if !s.isModerator(user, sub.Name) {
+		writeStatus(w, http.StatusForbidden)
+		return nil, false
+	}
+	return sub, true
+}
+
+func (s *Server) handleFriend(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.modSubreddit(w, user, params[0])
+	if !ok {
+		return
+	}
+	target, ok := s.users[strings.ToLower(r.Form.Get("name"))]
+	if !ok {
+		writeJSONErrors(w, []string{"USER_DOESNT_EXIST", "that user doesn't exist", "name"})
+		return
+	}
+	rel := r.Form.Get("type")
+	list := sub.relationships(rel)
+	if list == nil {
+		writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "type"})
+		return
+	}
+
+	entry := Relationship{
+		ID:   "rel_" + s.newID(),
+		Name: target.Name,
+		Date: time.Now(),
+		Note: r.Form.Get("note"),
+	}
+	if len([]rune(entry.Note)) > maxBanNoteLen {
+		writeJSONErrors(w, []string{"TOO_LONG", fmt.Sprintf("this is too long (max: %d)", maxBanNoteLen), "note"})
+		return
+	}
+
+	var action, details string
+	switch rel {
+	case reddit.RelBanned, reddit.RelMuted:
+		if s.isModerator(target, sub.Name) {
+			writeJSONErrors(w, []string{"CANT_RESTRICT_MODERATOR", "you can't do that to a moderator", "name"})
+			return
+		}
+		action = reddit.ModActionMuteUser
+		if rel == reddit.RelBanned {
+			action = reddit.ModActionBanUser
+			entry.Reason = r.Form.Get("ban_reason")
+			entry.Message = r.Form.Get("ban_message")
+			if len([]rune(entry.Reason)) > maxBanReasonLen {
+				writeJSONErrors(w, []string{"TOO_LONG", fmt.Sprintf("this is too long (max: %d)", maxBanReasonLen), "ban_reason"})
+				return
+			}
+			details = "permanent"
+			if d := r.Form.Get("duration"); d != "" {
+				days, err := strconv.Atoi(d)
+				if err != nil || days < 1 || days > maxBanDuration {
+					writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "duration"})
+					return
+				}
+				entry.Expires = entry.Date.Add(time.Duration(days) * 24 * time.Hour)
+				details = fmt.Sprintf("%d days", days)
+			}
+		}
+	case reddit.RelContributor:
+		action = reddit.ModActionAddContributor
+	case reddit.RelModeratorInvite:
+		if s.isModerator(target, sub.Name) {
+			writeJSONErrors(w, []string{"ALREADY_MODERATOR", "that user is already a moderator", "name"})
+			return
+		}
+		action = reddit.ModActionInviteModerator
+		entry.Permissions = parsePermissions(r.Form.Get("permissions"))
+	}
+
+	// a new entry replaces any existing one
+	removeRelationship(list, target.Name)
+	*list = append(*list, entry)
+	s.logUserModAction(sub, user, action, target, details)
+
+	// banned users are told, as on reddit
+	if rel == reddit.RelBanned {
+		body := "you have been banned from participating in r/" + sub.Name
+		if entry.Message != "" {
+			body += "\n\n" + entry.Message
+		}
+		target.Inbox = append(target.Inbox, Message{
+			ID:      s.newID(),
+			From:    "/r/" + sub.Name,
+			To:      target.Name,
+			Subject: "you've been banned from participating in r/" + sub.Name,
+			Body:    body,
+			Created: time.Now(),
+		})
+	}
+	writeJSONErrors(w)
+}
+
+func (s *Server) handleUnfriend(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.modSubreddit(w, user, params[0])
+	if !ok {
+		return
+	}
+	target, ok := s.users[strings.ToLower(r.Form.Get("name"))]
+	if !ok {
+		writeJSONErrors(w, []string{"USER_DOESNT_EXIST", "that user doesn't exist", "name"})
+		return
+	}
+
+	var action string
+	switch rel := r.Form.Get("type"); rel {
+	case reddit.RelModerator:
+		if !s.isModerator(target, sub.Name) {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+		for i, mod := range sub.Moderators {
+			if strings.EqualFold(mod, target.Name) {
+				sub.Moderators = append(sub.Moderators[:i], sub.Moderators[i+1:]...)
+				break
+			}
+		}
+		delete(sub.ModPermissions, strings.ToLower(target.Name))
+		action = reddit.ModActionRemoveModerator
+	default:
+		list := sub.relationships(rel)
+		if list == nil {
+			writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "type"})
+			return
+		}
+		if !removeRelationship(list, target.Name) {
+			writeStatus(w, http.StatusBadRequest)
+			return
+		}
+		switch rel {
+		case reddit.RelBanned:
+			action = reddit.ModActionUnbanUser
+		case reddit.RelMuted:
+			action = reddit.ModActionUnmuteUser
+		case reddit.RelContributor:
+			action = reddit.ModActionRemoveContributor
+		case reddit.RelModeratorInvite:
+			action = reddit.ModActionUninviteModerator
+		}
+	}
+
+	s.logUserModAction(sub, user, action, target, "")
+	writeJSONErrors(w)
+}
+
+func (s *Server) handleSetPermissions(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.modSubreddit(w, user, params[0])
+	if !ok {
+		return
+	}
+	name := r.Form.Get("name")
+	permissions := parsePermissions(r.Form.Get("permissions"))
+
+	switch r.Form.Get("type") {
+	case reddit.RelModerator:
+		target, ok := s.users[strings.ToLower(name)]
+		if !ok || !s.isModerator(target, sub.Name) {
+			writeJSONErrors(w, []string{"USER_DOESNT_EXIST", "that user isn't a moderator", "name"})
+			return
+		}
+		if permissions == nil {
+			delete(sub.ModPermissions, strings.ToLower(target.Name))
+		} else {
+			sub.ModPermissions[strings.ToLower(target.Name)] = permissions
+		}
+	case reddit.RelModeratorInvite:
+		invite, ok := findRelationship(sub.Invites, name)
+		if !ok {
+			writeJSONErrors(w, []string{"NO_INVITE_FOUND", "no invite found", "name"})
+			return
+		}
+		invite.Permissions = permissions
+	default:
+		writeJSONErrors(w, []string{"INVALID_OPTION", "that option is not valid", "type"})
+		return
+	}
+	writeJSONErrors(w)
+}
+
+func (s *Server) handleAcceptModInvite(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	sub, ok := s.subreddits[strings.ToLower(params[0])]
+	if !ok {
+		writeStatus(w, http.StatusNotFound)
+		return
+	}
+	invite, ok := findRelationship(sub.Invites, user.Name)
+	if !ok {
+		writeJSONErrors(w, []string{"NO_INVITE_FOUND", "no invite found", ""})
+		return
+	}
+
+	if invite.Permissions != nil {
+		sub.ModPermissions[strings.ToLower(user.Name)] = invite.Permissions
+	}
+	removeRelationship(&sub.Invites, user.Name)
+	sub.Moderators = append(sub.Moderators, user.Name)
+	s.logUserModAction(sub, user, reddit.ModActionAcceptModInvite, user, "")
+	writeJSONErrors(w)
+}
+
+// handleRelationshipListing returns a handler listing the users with
+// a relationship to a subreddit as a UserList, newest first. Only
+// moderators can see them.
+func (s *Server) handleRelationshipListing(rel string) func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	return func(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+		sub, ok := s.modSubreddit(w, user, params[0])
+		if !ok {
+			return
+		}
+
+		var entries []map[string]interface{}
+		if rel == reddit.RelModerator {
+			for _, name := range sub.Moderators {
+				permissions, ok := sub.ModPermissions[strings.ToLower(name)]
+				if !ok {
+					permissions = []string{"all"}
+				}
+				entry := s.relationshipData(Relationship{ID: "rel_mod_" + strings.ToLower(name), Name: name})
+				entry["mod_permissions"] = permissions
+				entries = append(entries, entry)
+			}
+		} else {
+			list := *sub.relationships(rel)
+			for i := len(list) - 1; i >= 0; i-- {
+				entries = append(entries, s.relationshipData(list[i]))
+			}
+		}
+
+		things := make([]interface{}, len(entries))
+		names := make([]string, len(entries))
+		for i, entry := range entries {
+			things[i] = entry
+			names[i] = entry["rel_id"].(string)
+		}
+		page := paginate(r, things, names)
+		page["kind"] = "UserList"
+		writeJSON(w, page)
+	}
+}
+
+// relationshipData returns the JSON representation of an entry in a
+// UserList. s.mu must be held.
+func (s *Server) relationshipData(entry Relationship) map[string]interface{} {
+	var date, daysLeft interface{}
+	if !entry.Date.IsZero() {
+		date = float64(entry.Date.Unix())
+	}
+	if !entry.Expires.IsZero() {
+		daysLeft = int(time.Until(entry.Expires).Hours() / 24)
+	}
+	return map[string]interface{}{
+		"name":      entry.Name,
+		"id":        s.authorFullname(entry.Name),
+		"rel_id":    entry.ID,
+		"date":      date,
+		"note":      entry.Note,
+		"days_left": daysLeft,
+	}
+}
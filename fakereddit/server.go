@@ -0,0 +1,307 @@
+// Package fakereddit provides an in-process stand-in for reddit that
+// can be used to test code built on the API package without network
+// access.
+package fakereddit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+)
+
+// defaults for new servers
+const (
+	defaultTokenLifetime   = time.Hour
+	defaultRateLimit       = 600
+	defaultRateLimitWindow = 10 * time.Minute
+)
+
+// Server is a fake reddit. The authentication endpoints normally on
+// www.reddit.com are served by RedditServer and the API endpoints
+// normally on oauth.reddit.com are served by OauthServer. All state
+// is held in memory.
+type Server struct {
+	RedditServer *httptest.Server
+	OauthServer  *httptest.Server
+
+	ClientID     string
+	ClientSecret string
+
+	// TokenLifetime is how long issued access tokens are valid for
+	TokenLifetime time.Duration
+	// RateLimit is the number of API requests allowed in each
+	// RateLimitWindow. Requests beyond this get a 429.
+	RateLimit       int
+	RateLimitWindow time.Duration
+	// MoreCommentsThreshold is the number of replies shown at each
+	// level of a comment tree before the rest are replaced by a
+	// "more" stub, and MaxCommentDepth is the depth beyond which
+	// threads are replaced by a "continue this thread" stub. Zero
+	// means no limit.
+	MoreCommentsThreshold int
+	MaxCommentDepth       int
+	// UnloadableComments holds the IDs of comments that
+	// /api/morechildren can't load. Like reddit, it returns a "more"
+	// stub for them again instead.
+	UnloadableComments []string
+
+	mu            sync.Mutex
+	users         map[string]*User
+	tokens        map[string]*session
+	refreshTokens map[string]*session
+	codes         map[string]string
+	subreddits    map[string]*Subreddit
+	posts         map[string]*Post
+	comments      map[string]*Comment
+	media         map[string]*MediaAsset
+	nextID        int64
+	windowStart   time.Time
+	windowUsed    int
+}
+
+// session is the account that a token was issued for. username is
+// blank for application-only tokens.
+type session struct {
+	username string
+	expiry   time.Time
+}
+
+// NewServer starts a new fake reddit which accepts the given client
+// ID and secret
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{
+		ClientID:        clientID,
+		ClientSecret:    clientSecret,
+		TokenLifetime:   defaultTokenLifetime,
+		RateLimit:       defaultRateLimit,
+		RateLimitWindow: defaultRateLimitWindow,
+		users:           map[string]*User{},
+		tokens:          map[string]*session{},
+		refreshTokens:   map[string]*session{},
+		codes:           map[string]string{},
+		subreddits:      map[string]*Subreddit{},
+		posts:           map[string]*Post{},
+		comments:        map[string]*Comment{},
+		media:           map[string]*MediaAsset{},
+		nextID:          1000,
+	}
+	s.RedditServer = httptest.NewServer(http.HandlerFunc(s.serveReddit))
+	s.OauthServer = httptest.NewServer(http.HandlerFunc(s.serveOauth))
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() {
+	s.RedditServer.Close()
+	s.OauthServer.Close()
+}
+
+// Configure points an API at this server
+func (s *Server) Configure(api *reddit.RedditAPI) {
+	redditURL, _ := url.Parse(s.RedditServer.URL)
+	oauthURL, _ := url.Parse(s.OauthServer.URL)
+	api.RedditBaseURL = redditURL
+	api.OauthBaseURL = oauthURL
+}
+
+// NewAPI creates an API configured to use this server for the given
+// username. The account still needs to log in.
+func (s *Server) NewAPI(username string) *reddit.RedditAPI {
+	api := reddit.NewRedditAPI(s.ClientID, s.ClientSecret, "fakereddit test client", username, false)
+	s.Configure(api)
+	return api
+}
+
+// newID returns a new unique base36 ID. s.mu must be held.
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.FormatInt(s.nextID, 36)
+}
+
+// idLess reports whether ID a was created before ID b
+func idLess(a, b string) bool {
+	x, _ := strconv.ParseInt(a, 36, 64)
+	y, _ := strconv.ParseInt(b, 36, 64)
+	return x < y
+}
+
+/* Routing */
+
+// route is an endpoint. A "*" in the pattern matches any single path
+// segment, and the matched segments are passed to the handler.
+type route struct {
+	method  string
+	pattern string
+	handler func(w http.ResponseWriter, r *http.Request, user *User, params []string)
+	// write routes require a user token
+	write bool
+}
+
+// match checks the path against the pattern, returning the segments
+// matched by wildcards
+func (rt *route) match(method, path string) ([]string, bool) {
+	if method != rt.method {
+		return nil, false
+	}
+	patternParts := strings.Split(strings.Trim(rt.pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+
+	var params []string
+	for i, part := range patternParts {
+		if part == "*" {
+			params = append(params, pathParts[i])
+		} else if !strings.EqualFold(part, pathParts[i]) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// serveReddit serves the authentication endpoints and stands in for
+// reddit's media storage
+func (s *Server) serveReddit(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case reddit.RedditEndpointLogin:
+		s.handleAccessToken(w, r)
+	case mediaUploadPath:
+		s.handleMediaUpload(w, r)
+	default:
+		writeStatus(w, http.StatusNotFound)
+	}
+}
+
+// serveOauth authenticates the request and dispatches it to the
+// matching route
+func (s *Server) serveOauth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// rate limit
+	if !s.takeRateLimit(w) {
+		writeStatus(w, http.StatusTooManyRequests)
+		return
+	}
+
+	// check the bearer token
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+		writeStatus(w, http.StatusUnauthorized)
+		return
+	}
+	sess, ok := s.tokens[auth[len("bearer "):]]
+	if !ok || time.Now().After(sess.expiry) {
+		writeStatus(w, http.StatusUnauthorized)
+		return
+	}
+	user := s.users[strings.ToLower(sess.username)]
+
+	if err := r.ParseForm(); err != nil {
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+
+	// .json is optional on most reddit endpoints
+	path := strings.TrimSuffix(r.URL.Path, ".json")
+	for _, rt := range s.routes() {
+		params, ok := rt.match(r.Method, path)
+		if !ok {
+			continue
+		}
+		if rt.write && user == nil {
+			writeStatus(w, http.StatusForbidden)
+			return
+		}
+		rt.handler(w, r, user, params)
+		return
+	}
+	writeStatus(w, http.StatusNotFound)
+}
+
+// takeRateLimit counts a request against the rate limit and sets the
+// rate limit headers, returning false if the limit has been exceeded.
+// s.mu must be held.
+func (s *Server) takeRateLimit(w http.ResponseWriter) bool {
+	now := time.Now()
+	if now.Sub(s.windowStart) >= s.RateLimitWindow {
+		s.windowStart = now
+		s.windowUsed = 0
+	}
+	s.windowUsed++
+
+	remaining := s.RateLimit - s.windowUsed
+	if remaining < 0 {
+		remaining = 0
+	}
+	reset := s.windowStart.Add(s.RateLimitWindow).Sub(now)
+	w.Header().Set("X-Ratelimit-Used", strconv.Itoa(s.windowUsed))
+	w.Header().Set("X-Ratelimit-Remaining", strconv.Itoa(remaining)+".0")
+	w.Header().Set("X-Ratelimit-Reset", strconv.Itoa(int(reset/time.Second)))
+
+	return s.windowUsed <= s.RateLimit
+}
+
+/* Response helpers */
+
+// writeJSON writes v as JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeStatus writes an error status in the same format as reddit
+func writeStatus(w http.ResponseWriter, status int) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": http.StatusText(status),
+		"error":   status,
+	})
+}
+
+// writeJSONErrors writes errors in the format used by api_type=json
+// endpoints. Each error is a code, message and field.
+func writeJSONErrors(w http.ResponseWriter, errs ...[]string) {
+	if errs == nil {
+		errs = [][]string{}
+	}
+	writeJSON(w, map[string]interface{}{
+		"json": map[string]interface{}{
+			"errors": errs,
+		},
+	})
+}
+
+// writeJSONData writes a successful api_type=json response with data
+func writeJSONData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, map[string]interface{}{
+		"json": map[string]interface{}{
+			"errors": [][]string{},
+			"data":   data,
+		},
+	})
+}
+
+// formBool reads a boolean form value
+func formBool(r *http.Request, key string) bool {
+	b, _ := strconv.ParseBool(r.Form.Get(key))
+	return b
+}
+
+// splitFullname splits a fullname into its kind and ID
+func splitFullname(name string) (string, string) {
+	parts := strings.SplitN(name, "_", 2)
+	if len(parts) != 2 {
+		return "", name
+	}
+	return parts[0], parts[1]
+}
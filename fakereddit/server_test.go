@@ -0,0 +1,170 @@
+package fakereddit_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	reddit "github.com/joshbarrass/goreddit/API"
+	"github.com/joshbarrass/goreddit/fakereddit"
+)
+
+// newServer starts a fake reddit with the user bot and the subreddit
+// test. Close the server when done.
+func newServer() *fakereddit.Server {
+	s := fakereddit.NewServer("id", "secret")
+	s.AddUser("bot", "pw")
+	s.AddSubreddit("test", "bot")
+	return s
+}
+
+// checkStatus checks that err is an *HTTPStatusError with the status
+func checkStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var statusErr *reddit.HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+		t.Errorf("got %v, want a %d *HTTPStatusError", err, status)
+	}
+}
+
+func TestPasswordLogin(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+
+	api := s.NewAPI("bot")
+	if err := api.Account.PasswordLogin("wrong"); err == nil {
+		t.Error("logged in with the wrong password")
+	}
+	if err := api.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	me, err := api.RequestMe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Username != "bot" {
+		t.Errorf("got username %q", me.Username)
+	}
+
+	// tokens stop working once expired
+	s.ExpireTokens()
+	_, err = api.RequestMe()
+	checkStatus(t, err, http.StatusUnauthorized)
+}
+
+func TestClientSecret(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+
+	api := reddit.NewRedditAPI("id", "wrong", "test", "bot", false)
+	s.Configure(api)
+	if err := api.Account.PasswordLogin("pw"); err == nil {
+		t.Error("logged in with the wrong client secret")
+	}
+}
+
+func TestCodeLogin(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	s.AddAuthCode("code", "bot")
+
+	api := s.NewAPI("")
+	if err := api.Account.CodeLogin("code", "http://localhost/callback"); err != nil {
+		t.Fatal(err)
+	}
+	// codes can only be used once
+	if err := s.NewAPI("").Account.CodeLogin("code", "http://localhost/callback"); err == nil {
+		t.Error("a code was used twice")
+	}
+
+	// the refresh token gets a new access token
+	s.ExpireTokens()
+	if err := api.Account.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	me, err := api.RequestMe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.Username != "bot" {
+		t.Errorf("got username %q", me.Username)
+	}
+}
+
+func TestApplicationOnly(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	s.AddPost("test", "bot", "title", "body")
+
+	api := s.NewAPI("")
+	if err := api.Account.ClientCredentialsLogin(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reading works, but there is no user to post as
+	posts := api.RequestSubredditListing("test", reddit.SortNew, "", nil)
+	if !posts.Next() {
+		t.Fatalf("got no posts: %v", posts.Err())
+	}
+	req, _ := http.NewRequest(http.MethodPost, s.OauthServer.URL+reddit.OauthEndpointSubmitPost, nil)
+	req.Header.Set("Authorization", "bearer "+api.Account.Token.Token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got status %d submitting without a user", resp.StatusCode)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	s.RateLimit = 1
+
+	api := s.NewAPI("bot")
+	api.RateLimitMode = reddit.RateLimitIgnore
+	api.RetryPolicy = reddit.RetryPolicy{}
+	if err := api.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.RequestMe(); err != nil {
+		t.Fatal(err)
+	}
+	if limit := api.RateLimit(); limit.Used != 1 || limit.Remaining != 0 {
+		t.Errorf("got rate limit %+v", limit)
+	}
+
+	_, err := api.RequestMe()
+	var rateErr *reddit.RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Errorf("got %v, want a *RateLimitError", err)
+	}
+}
+
+func TestSubmitTextPost(t *testing.T) {
+	s := newServer()
+	defer s.Close()
+	api := s.NewAPI("bot")
+	if err := api.Account.PasswordLogin("pw"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := api.RequestSubmitTextPost("test", "title", "body", false, false, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	post, ok := s.Post(data.ID)
+	if !ok {
+		t.Fatalf("post %s not stored", data.ID)
+	}
+	if post.Author != "bot" || post.Subreddit != "test" || post.Title != "title" || post.Body != "body" || !post.Spoiler {
+		t.Errorf("got %+v", post)
+	}
+
+	_, err = api.RequestSubmitTextPost("nonexistent", "title", "body", false, false, false, true)
+	if !errors.Is(err, reddit.ErrCodeSubredditNoExist) {
+		t.Errorf("got %v, want SUBREDDIT_NOEXIST", err)
+	}
+}
@@ -0,0 +1,536 @@
+package fakereddit
+
+import (
+	"strings"
+	"time"
+)
+
+// User is an account on the fake reddit
+type User struct {
+	Name     string
+	ID       string
+	Created  time.Time
+	password string
+	// Inbox holds messages sent to the user
+	Inbox []Message
+	// Votes holds the user's votes by fullname
+	Votes map[string]int
+	// Saved holds the things saved by the user, oldest first
+	Saved []SavedThing
+	// Hidden holds the fullnames of posts hidden by the user, oldest
+	// first
+	Hidden []string
+}
+
+// SavedThing is a post or comment saved by a user
+type SavedThing struct {
+	Fullname string
+	Category string
+}
+
+// Report is a report made by a user
+type Report struct {
+	Reason string
+	User   string
+}
+
+// Subreddit is a subreddit on the fake reddit
+type Subreddit struct {
+	Name       string
+	ID         string
+	Moderators []string
+	Stylesheet string
+	Images     []StylesheetImage
+	// Modmail holds messages sent to the subreddit
+	Modmail []Message
+	// ModLog holds the actions taken by moderators, oldest first
+	ModLog []ModAction
+	// ModPermissions holds the permissions of moderators by lowercase
+	// name. Moderators without an entry have all permissions.
+	ModPermissions map[string][]string
+	Banned         []Relationship
+	Muted          []Relationship
+	Contributors   []Relationship
+	// Invites holds the pending invitations to moderate
+	Invites []Relationship
+	// Conversations holds the subreddit's new modmail, oldest first
+	Conversations []Conversation
+}
+
+// Conversation is a new modmail conversation. Participant is the user
+// the moderators are talking to, and is blank for discussions between
+// moderators.
+type Conversation struct {
+	ID          string
+	Subject     string
+	Participant string
+	State       int
+	Highlighted bool
+	Auto        bool
+	Messages    []ConversationMessage
+	Actions     []ConversationAction
+	// Read holds the lowercase names of the users who have read the
+	// latest message
+	Read map[string]bool
+}
+
+// ConversationMessage is a message in a modmail conversation
+type ConversationMessage struct {
+	ID       string
+	Author   string
+	Body     string
+	Internal bool
+	Created  time.Time
+}
+
+// ConversationAction is a moderator action on a modmail conversation
+type ConversationAction struct {
+	ID        string
+	Type      int
+	Moderator string
+	Created   time.Time
+}
+
+// Relationship is a user's entry in one of a subreddit's lists
+type Relationship struct {
+	ID   string
+	Name string
+	Date time.Time
+	Note string
+	// Reason, Message and Expires are only used for bans. Expires is
+	// zero for permanent bans.
+	Reason  string
+	Message string
+	Expires time.Time
+	// Permissions is only used for invitations
+	Permissions []string
+}
+
+// ModAction is an entry in a subreddit's moderation log
+type ModAction struct {
+	ID             string
+	Action         string
+	Moderator      string
+	TargetFullname string
+	TargetAuthor   string
+	Details        string
+	Created        time.Time
+}
+
+// StylesheetImage is an image uploaded for use in a stylesheet
+type StylesheetImage struct {
+	Name string
+	URL  string
+	Link string
+}
+
+// Post is a submission on the fake reddit
+type Post struct {
+	ID            string
+	Subreddit     string
+	Author        string
+	Title         string
+	Body          string
+	URL           string
+	IsSelf        bool
+	IsVideo       bool
+	PosterURL     string
+	Gallery       []GalleryItem
+	Poll          *Poll
+	Ad            bool
+	NSFW          bool
+	Spoiler       bool
+	SendReplies   bool
+	FlairID       string
+	FlairText     string
+	CollectionID  string
+	EventStart    time.Time
+	EventEnd      time.Time
+	Stickied      bool
+	StickyNum     int
+	ContestMode   bool
+	Locked        bool
+	Removed       bool
+	Spam          bool
+	Approved      bool
+	Deleted       bool
+	Reports       []Report
+	IgnoreReports bool
+	Distinguished string
+	SuggestedSort string
+	RemovalReason string
+	ModNote       string
+	Score         int64
+	Created       time.Time
+	Edited        time.Time
+}
+
+// Fullname returns the fullname of the post
+func (p *Post) Fullname() string {
+	return "t3_" + p.ID
+}
+
+// GalleryItem is an image in a gallery post
+type GalleryItem struct {
+	MediaID     string
+	Caption     string
+	OutboundURL string
+}
+
+// Poll is the poll in a poll post
+type Poll struct {
+	Options   []PollOption
+	VotingEnd time.Time
+	// Votes maps lowercase usernames to the ID of the option they
+	// voted for
+	Votes map[string]string
+}
+
+// PollOption is an option in a poll
+type PollOption struct {
+	ID   string
+	Text string
+}
+
+// MediaAsset is a file uploaded for use in a post. URL is blank until
+// the file has been uploaded.
+type MediaAsset struct {
+	ID       string
+	Filename string
+	MimeType string
+	Data     []byte
+	URL      string
+}
+
+// Comment is a comment on the fake reddit
+type Comment struct {
+	ID string
+	// PostID is the ID of the post the comment is on
+	PostID string
+	// ParentID is the fullname of the parent post or comment
+	ParentID      string
+	Author        string
+	Body          string
+	Removed       bool
+	Spam          bool
+	Approved      bool
+	Deleted       bool
+	Reports       []Report
+	IgnoreReports bool
+	Locked        bool
+	Distinguished string
+	Stickied      bool
+	RemovalReason string
+	ModNote       string
+	Score         int64
+	Created       time.Time
+	Edited        time.Time
+}
+
+// Fullname returns the fullname of the comment
+func (c *Comment) Fullname() string {
+	return "t1_" + c.ID
+}
+
+// Message is a private message or modmail
+type Message struct {
+	ID      string
+	From    string
+	To      string
+	Subject string
+	Body    string
+	Created time.Time
+}
+
+/* Setup */
+
+// AddUser creates an account that can log in with the given password
+func (s *Server) AddUser(username, password string) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := &User{
+		Name:     username,
+		ID:       s.newID(),
+		Created:  time.Now(),
+		password: password,
+		Votes:    map[string]int{},
+	}
+	s.users[strings.ToLower(username)] = user
+	return *user
+}
+
+// AddSubreddit creates a subreddit moderated by the given users
+func (s *Server) AddSubreddit(name string, moderators ...string) Subreddit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := &Subreddit{
+		Name:           name,
+		ID:             s.newID(),
+		Moderators:     moderators,
+		ModPermissions: map[string][]string{},
+	}
+	s.subreddits[strings.ToLower(name)] = sub
+	return *sub
+}
+
+// AddStylesheetImage adds an image to a subreddit's stylesheet
+// images
+func (s *Server) AddStylesheetImage(subreddit string, image StylesheetImage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sub, ok := s.subreddits[strings.ToLower(subreddit)]; ok {
+		sub.Images = append(sub.Images, image)
+	}
+}
+
+// AddPost creates a self post and returns it
+func (s *Server) AddPost(subreddit, author, title, body string) Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post := s.addPost(subreddit, author, title)
+	post.IsSelf = true
+	post.Body = body
+	return *post
+}
+
+// addPost creates a post with no content. s.mu must be held.
+func (s *Server) addPost(subreddit, author, title string) *Post {
+	if sub, ok := s.subreddits[strings.ToLower(subreddit)]; ok {
+		subreddit = sub.Name
+	}
+	post := &Post{
+		ID:          s.newID(),
+		Subreddit:   subreddit,
+		Author:      author,
+		Title:       title,
+		SendReplies: true,
+		Score:       1,
+		Created:     time.Now(),
+	}
+	post.URL = "https://www.reddit.com" + post.permalink()
+	s.posts[post.ID] = post
+	return post
+}
+
+// AddComment creates a comment replying to the post or comment with
+// the given fullname
+func (s *Server) AddComment(parent, author, body string) (Comment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, ok := s.addComment(parent, author, body)
+	if !ok {
+		return Comment{}, false
+	}
+	return *comment, true
+}
+
+// addComment creates a comment. s.mu must be held.
+func (s *Server) addComment(parent, author, body string) (*Comment, bool) {
+	var postID string
+	kind, id := splitFullname(parent)
+	switch kind {
+	case "t3":
+		if _, ok := s.posts[id]; !ok {
+			return nil, false
+		}
+		postID = id
+	case "t1":
+		c, ok := s.comments[id]
+		if !ok {
+			return nil, false
+		}
+		postID = c.PostID
+	default:
+		return nil, false
+	}
+
+	comment := &Comment{
+		ID:       s.newID(),
+		PostID:   postID,
+		ParentID: parent,
+		Author:   author,
+		Body:     body,
+		Score:    1,
+		Created:  time.Now(),
+	}
+	s.comments[comment.ID] = comment
+	return comment, true
+}
+
+// AddConversation starts a new modmail conversation with a subreddit.
+// Conversations started by moderators are discussions between them.
+func (s *Server) AddConversation(subreddit, author, subject, body string) (Conversation, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subreddits[strings.ToLower(subreddit)]
+	if !ok {
+		return Conversation{}, false
+	}
+	return s.addConversation(sub, author, subject, body).copy(), true
+}
+
+/* Inspection */
+
+// User returns a copy of a user
+func (s *Server) User(username string) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[strings.ToLower(username)]
+	if !ok {
+		return User{}, false
+	}
+	u := *user
+	u.Inbox = append([]Message(nil), user.Inbox...)
+	u.Votes = map[string]int{}
+	for name, dir := range user.Votes {
+		u.Votes[name] = dir
+	}
+	u.Saved = append([]SavedThing(nil), user.Saved...)
+	u.Hidden = append([]string(nil), user.Hidden...)
+	return u, true
+}
+
+// Subreddit returns a copy of a subreddit
+func (s *Server) Subreddit(name string) (Subreddit, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subreddits[strings.ToLower(name)]
+	if !ok {
+		return Subreddit{}, false
+	}
+	c := *sub
+	c.Moderators = append([]string(nil), sub.Moderators...)
+	c.Images = append([]StylesheetImage(nil), sub.Images...)
+	c.Modmail = append([]Message(nil), sub.Modmail...)
+	c.ModLog = append([]ModAction(nil), sub.ModLog...)
+	c.ModPermissions = map[string][]string{}
+	for name, permissions := range sub.ModPermissions {
+		c.ModPermissions[name] = append([]string(nil), permissions...)
+	}
+	c.Banned = append([]Relationship(nil), sub.Banned...)
+	c.Muted = append([]Relationship(nil), sub.Muted...)
+	c.Contributors = append([]Relationship(nil), sub.Contributors...)
+	c.Invites = append([]Relationship(nil), sub.Invites...)
+	c.Conversations = nil
+	for _, conv := range sub.Conversations {
+		c.Conversations = append(c.Conversations, conv.copy())
+	}
+	return c, true
+}
+
+// Post returns a copy of the post with the given ID
+func (s *Server) Post(id string) (Post, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return Post{}, false
+	}
+	p := *post
+	p.Gallery = append([]GalleryItem(nil), post.Gallery...)
+	p.Reports = append([]Report(nil), post.Reports...)
+	if post.Poll != nil {
+		poll := *post.Poll
+		poll.Options = append([]PollOption(nil), post.Poll.Options...)
+		poll.Votes = map[string]string{}
+		for user, option := range post.Poll.Votes {
+			poll.Votes[user] = option
+		}
+		p.Poll = &poll
+	}
+	return p, true
+}
+
+// Comment returns a copy of the comment with the given ID
+func (s *Server) Comment(id string) (Comment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return Comment{}, false
+	}
+	c := *comment
+	c.Reports = append([]Report(nil), comment.Reports...)
+	return c, true
+}
+
+// MediaAsset returns a copy of the media asset with the given ID
+func (s *Server) MediaAsset(id string) (MediaAsset, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	asset, ok := s.media[id]
+	if !ok {
+		return MediaAsset{}, false
+	}
+	return *asset, true
+}
+
+// mediaByURL finds an uploaded media asset by its URL. s.mu must be
+// held.
+func (s *Server) mediaByURL(u string) (*MediaAsset, bool) {
+	for _, asset := range s.media {
+		if asset.URL != "" && asset.URL == u {
+			return asset, true
+		}
+	}
+	return nil, false
+}
+
+// linkSubmitted reports whether a link has already been posted to the
+// subreddit. s.mu must be held.
+func (s *Server) linkSubmitted(subreddit, link string) bool {
+	for _, post := range s.posts {
+		if !post.IsSelf && strings.EqualFold(post.Subreddit, subreddit) && post.URL == link {
+			return true
+		}
+	}
+	return false
+}
+
+// isModerator reports whether the user moderates the subreddit. s.mu
+// must be held.
+func (s *Server) isModerator(user *User, subreddit string) bool {
+	sub, ok := s.subreddits[strings.ToLower(subreddit)]
+	if !ok || user == nil {
+		return false
+	}
+	for _, mod := range sub.Moderators {
+		if strings.EqualFold(mod, user.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// permalink returns the path to a post
+func (p *Post) permalink() string {
+	return "/r/" + p.Subreddit + "/comments/" + p.ID + "/" + slug(p.Title) + "/"
+}
+
+// slug converts a title into the form used in permalinks
+func slug(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if b.Len() >= 50 {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteRune('_')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
@@ -0,0 +1,160 @@
+package fakereddit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// limits on user text
+const (
+	maxCommentLength  = 10000
+	maxSelfTextLength = 40000
+)
+
+// checkText checks the text of a comment or self post, returning the
+// error triple if it is invalid
+func checkText(text string, maxLength int) []string {
+	if strings.TrimSpace(text) == "" {
+		return []string{"NO_TEXT", "we need something here", "text"}
+	}
+	if len([]rune(text)) > maxLength {
+		return []string{"TOO_LONG", fmt.Sprintf("this is too long (max: %d)", maxLength), "text"}
+	}
+	return nil
+}
+
+func (s *Server) handleComment(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	parent := r.Form.Get("thing_id")
+	text := r.Form.Get("text")
+	if errs := checkText(text, maxCommentLength); errs != nil {
+		writeJSONErrors(w, errs)
+		return
+	}
+
+	// find the post being replied to
+	var post *Post
+	kind, id := splitFullname(parent)
+	switch kind {
+	case "t3":
+		post = s.posts[id]
+		if post != nil && post.Deleted {
+			writeJSONErrors(w, []string{"DELETED_LINK", "the link you are commenting on has been deleted", "parent"})
+			return
+		}
+	case "t1":
+		if comment, ok := s.comments[id]; ok {
+			if comment.Deleted {
+				writeJSONErrors(w, []string{"DELETED_COMMENT", "that comment has been deleted", "parent"})
+				return
+			}
+			post = s.posts[comment.PostID]
+			if comment.Locked && !s.isModerator(user, post.Subreddit) {
+				writeJSONErrors(w, []string{"THREAD_LOCKED", "comments are locked", "parent"})
+				return
+			}
+		}
+	}
+	if post == nil {
+		writeJSONErrors(w, []string{"NO_THING_ID", "no thing found", "thing_id"})
+		return
+	}
+	if post.Locked && !s.isModerator(user, post.Subreddit) {
+		writeJSONErrors(w, []string{"THREAD_LOCKED", "comments are locked", "parent"})
+		return
+	}
+
+	comment, _ := s.addComment(parent, user.Name, text)
+	thing := s.commentThing(comment, 0, user)
+	writeJSONData(w, map[string]interface{}{
+		"things": []interface{}{thing},
+	})
+}
+
+func (s *Server) handleEditUserText(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	text := r.Form.Get("text")
+	now := time.Now()
+
+	var thing map[string]interface{}
+	kind, id := splitFullname(r.Form.Get("thing_id"))
+	switch kind {
+	case "t3":
+		post, ok := s.posts[id]
+		if !ok || post.Deleted {
+			writeJSONErrors(w, []string{"NO_THING_ID", "no thing found", "thing_id"})
+			return
+		}
+		if !strings.EqualFold(post.Author, user.Name) {
+			writeJSONErrors(w, []string{"NOT_AUTHOR", "you can't do that", "thing_id"})
+			return
+		}
+		if !post.IsSelf {
+			writeJSONErrors(w, []string{"NO_SELFS", "only self posts can be edited", "thing_id"})
+			return
+		}
+		if errs := checkText(text, maxSelfTextLength); errs != nil {
+			writeJSONErrors(w, errs)
+			return
+		}
+		post.Body = text
+		post.Edited = now
+		thing = s.postThing(post, user)
+	case "t1":
+		comment, ok := s.comments[id]
+		if !ok || comment.Deleted {
+			writeJSONErrors(w, []string{"NO_THING_ID", "no thing found", "thing_id"})
+			return
+		}
+		if !strings.EqualFold(comment.Author, user.Name) {
+			writeJSONErrors(w, []string{"NOT_AUTHOR", "you can't do that", "thing_id"})
+			return
+		}
+		if errs := checkText(text, maxCommentLength); errs != nil {
+			writeJSONErrors(w, errs)
+			return
+		}
+		comment.Body = text
+		comment.Edited = now
+		thing = s.commentThing(comment, 0, user)
+	default:
+		writeJSONErrors(w, []string{"NO_THING_ID", "no thing found", "thing_id"})
+		return
+	}
+
+	writeJSONData(w, map[string]interface{}{
+		"things": []interface{}{thing},
+	})
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request, user *User, params []string) {
+	kind, id := splitFullname(r.Form.Get("id"))
+	switch kind {
+	case "t3":
+		post, ok := s.posts[id]
+		if !ok {
+			writeStatus(w, http.StatusNotFound)
+			return
+		}
+		if !strings.EqualFold(post.Author, user.Name) {
+			writeStatus(w, http.StatusForbidden)
+			return
+		}
+		post.Deleted = true
+	case "t1":
+		comment, ok := s.comments[id]
+		if !ok {
+			writeStatus(w, http.StatusNotFound)
+			return
+		}
+		if !strings.EqualFold(comment.Author, user.Name) {
+			writeStatus(w, http.StatusForbidden)
+			return
+		}
+		comment.Deleted = true
+	default:
+		writeStatus(w, http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]interface{}{})
+}
@@ -1,5 +1,8 @@
 module github.com/joshbarrass/goreddit
 
-go 1.12
+go 1.13
 
-require github.com/sirupsen/logrus v1.4.2
+require (
+	github.com/sirupsen/logrus v1.4.2
+	gopkg.in/yaml.v2 v2.4.0
+)